@@ -0,0 +1,807 @@
+// Package querygen provides the analyzer which generates Vars structs
+// for query constants, for use with the querygen CLI, gopls or other
+// go/analysis drivers.
+package querygen
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/charmbracelet/log"
+	"github.com/sourcegraph/conc/pool"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/querygen/internal"
+)
+
+// Analyzer reports generated files which are missing, stale or orphaned,
+// with suggested fixes regenerating them.
+//
+// Generated files are only written directly with the -write flag, which
+// the querygen command sets by default. Since fixes can only edit existing
+// files, missing files are created with just a header when applying fixes
+// using -fix, and their fixes fill them in.
+var Analyzer = &analysis.Analyzer{
+	Name:             "querygen",
+	Doc:              "Generate Vars structs for SQL query constants",
+	Run:              run,
+	RunDespiteErrors: true,
+	FactTypes:        []analysis.Fact{new(internal.QueryFact)},
+}
+
+func init() {
+	Analyzer.Flags.StringVar(&globalLogLevel, "log-level", globalLogLevel,
+		"Log level: one of debug, info, warn, error, or fatal")
+	Analyzer.Flags.BoolVar(&globalWriteMode, "write", false,
+		"Create, update and remove generated files directly instead of only reporting them "+
+			"(the default for the querygen command)")
+	Analyzer.Flags.BoolVar(&globalCheckMode, "check", false,
+		"Only report stale, missing or orphaned generated files, even with -write")
+}
+
+type analysisResult struct {
+	file    *token.File         // non-nil
+	astFile *ast.File           // non-nil
+	wanted  []internal.GoStruct // may be empty
+}
+
+type queryGenFileData struct {
+	file    *token.File // may be nil
+	astFile *ast.File   // may be nil
+	// 2-phased initialization, may be empty
+	wanted []internal.GoStruct
+}
+
+var globalLogLevel string = "info"
+
+// globalWriteMode is set when querygen should write generated files
+// directly, rather than only suggesting fixes for them.
+var globalWriteMode bool
+
+// globalCheckMode is set when querygen should only verify that
+// generated files are up-to-date, without writing anything.
+// It takes precedence over globalWriteMode.
+var globalCheckMode bool
+
+func initLogger() (*log.Logger, error) {
+	level, err := log.ParseLevel(globalLogLevel)
+	if err != nil {
+		return nil, err
+	}
+	styles := log.DefaultStyles()
+	for lvl, value := range styles.Levels {
+		styles.Levels[lvl] = value.MaxWidth(8)
+	}
+	logger := log.NewWithOptions(os.Stderr, log.Options{ReportTimestamp: true, Level: level})
+	logger.SetTimeFormat("15:04:05")
+	logger.SetStyles(styles)
+	return logger, nil
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	if isStandardLibrary(pass) {
+		// Facts are computed for every dependency, but the standard
+		// library can't contain queries.
+		return nil, nil
+	}
+	logger, err := initLogger()
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+
+	queryGenFiles, results := gatherQueryGenFileData(logger, pass, settings)
+	logger.Debug("gathered query file data",
+		"numQueryGenFiles", len(queryGenFiles),
+		"numNonQueryGenFiles", len(results))
+
+	// Multiple source files may map to the same generated file (e.g. a.go
+	// and a_query.go), so merge their structs in a deterministic order.
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].file.Name() < results[j].file.Name()
+	})
+	for _, result := range results {
+		logger.Debug("got result", "path", result.file.Name(), "wanted", len(result.wanted))
+		if len(result.wanted) == 0 {
+			continue
+		}
+		if !strings.HasSuffix(result.file.Name(), ".go") {
+			// Erm, got a query in non-Go code?
+			continue
+		}
+
+		queryGenFilename := getQueryGenFilename(result.file.Name(), settings.GeneratedFileSuffix)
+		fileData, ok := queryGenFiles[queryGenFilename]
+		if !ok {
+			fileData = &queryGenFileData{nil, nil, nil}
+			queryGenFiles[queryGenFilename] = fileData
+		}
+		fileData.wanted = appendUniqueStructs(pass, fileData.wanted, result.wanted)
+	}
+	logger.Debug("updated query file data", "numQueryGenFiles", len(queryGenFiles))
+
+	exportQueryFacts(pass, results)
+
+	// Drivers only print diagnostics for the packages they were asked to
+	// analyze, but -fix applies the fixes for dependencies too, so only
+	// look up the root packages when files may be modified.
+	writeMode := globalWriteMode && !globalCheckMode
+	if (writeMode || isFixMode()) && !isRootPackage(logger, pass.Pkg) {
+		// Only analyzed to compute facts for the packages importing it.
+		logger.Debug("skipping generated files for dependency", "pkg", pass.Pkg.Path())
+		return nil, nil
+	}
+	if writeMode && !isFixMode() {
+		updateQueryGenFiles(logger, pass, settings, queryGenFiles)
+		return nil, nil
+	}
+	checkQueryGenFiles(logger, pass, settings, queryGenFiles)
+
+	return nil, nil
+}
+
+// exportQueryFacts exports a QueryFact for every package-level query
+// constant, so that queries in other packages can use it as a fragment.
+func exportQueryFacts(pass *analysis.Pass, results []analysisResult) {
+	for _, result := range results {
+		for i := range result.wanted {
+			goStruct := &result.wanted[i]
+			obj, ok := pass.TypesInfo.Defs[goStruct.QueryConst].(*types.Const)
+			if !ok || obj.Parent() != pass.Pkg.Scope() {
+				continue
+			}
+			pass.ExportObjectFact(obj, internal.NewQueryFact(pass, goStruct))
+		}
+	}
+}
+
+// appendUniqueStructs appends newStructs to existing, reporting and
+// skipping structs whose type name is already taken.
+//
+// Type names can collide when merging structs from different source
+// files into the same generated file, or due to //querygen:name.
+func appendUniqueStructs(pass *analysis.Pass, existing []internal.GoStruct, newStructs []internal.GoStruct) []internal.GoStruct {
+	for _, newStruct := range newStructs {
+		isDuplicate := false
+		for _, goStruct := range existing {
+			if goStruct.TypeName != newStruct.TypeName {
+				continue
+			}
+			isDuplicate = true
+			pass.Report(analysis.Diagnostic{
+				Pos: newStruct.QueryConst.Pos(),
+				End: newStruct.QueryConst.End(),
+				Message: fmt.Sprintf("generated type %s for %s in %s conflicts with the one for %s in %s",
+					newStruct.TypeName, newStruct.QueryConst.Name, newStruct.SourceFile,
+					goStruct.QueryConst.Name, goStruct.SourceFile),
+				Related: []analysis.RelatedInformation{{
+					Pos:     goStruct.QueryConst.Pos(),
+					End:     goStruct.QueryConst.End(),
+					Message: fmt.Sprintf("%s first generated here", goStruct.TypeName),
+				}},
+			})
+			break
+		}
+		if !isDuplicate {
+			existing = append(existing, newStruct)
+		}
+	}
+	return existing
+}
+
+// isFixMode reports whether multichecker was asked to apply suggested fixes
+// using -fix, in which case files must only be modified via those fixes.
+func isFixMode() bool {
+	fixFlag := flag.Lookup("fix")
+	return fixFlag != nil && fixFlag.Value.String() == "true"
+}
+
+// loadSettings returns the settings for the package being analyzed,
+// from the configuration file of its module.
+func loadSettings(pass *analysis.Pass) (*internal.Settings, error) {
+	settings := internal.DefaultSettings()
+	for _, astFile := range pass.Files {
+		if file := pass.Fset.File(astFile.FileStart); file != nil {
+			var err error
+			settings, err = internal.LoadSettings(filepath.Dir(file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return &settings, nil
+}
+
+// Categories for diagnostics reported in check mode.
+const (
+	diagnosticCategoryMissing  = "missing"
+	diagnosticCategoryStale    = "stale"
+	diagnosticCategoryOrphaned = "orphaned"
+	// diagnosticCategoryHandWritten is also used outside check mode,
+	// since such files are skipped.
+	diagnosticCategoryHandWritten = "handwritten"
+)
+
+// checkQueryGenFiles reports a diagnostic for every generated file which
+// doesn't match what updateQueryGenFiles would write, without touching disk
+// unless applying fixes.
+//
+// The diagnostics carry a SuggestedFix with the same changes
+// updateQueryGenFiles would make, so that they can be applied using -fix
+// or by an editor. File creation cannot be expressed as a TextEdit, so
+// diagnostics for missing files only have a fix with -fix, which creates
+// the file first.
+//
+// Variants of a package, such as p and its test variant, share the
+// generated files for non-test source files, so the same diagnostics are
+// reported for each variant. Drivers de-duplicate them, as they do for
+// other diagnostics in source files shared by several packages.
+func checkQueryGenFiles(logger *log.Logger, pass *analysis.Pass, settings *internal.Settings, queryGenFiles map[string]*queryGenFileData) {
+	paths := make([]string, 0, len(queryGenFiles))
+	for path := range queryGenFiles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	staleFileCount := 0
+	missingFileCount := 0
+	orphanedFileCount := 0
+	for _, path := range paths {
+		fileData := queryGenFiles[path]
+		logger := logger.With("path", path)
+		if fileData.file == nil {
+			logger.Debug("generated file is missing")
+			diagnostic := analysis.Diagnostic{
+				Pos:      fileData.wanted[0].QueryConst.Pos(),
+				Category: diagnosticCategoryMissing,
+				Message:  fmt.Sprintf("missing generated file %s; run querygen -write to create it", filepath.Base(path)),
+			}
+			if isFixMode() {
+				fix, err := fileData.createFileFix(pass, path, settings)
+				if err != nil {
+					logger.Warn("failed to create file for fix", "err", err)
+				} else if fix != nil {
+					diagnostic.SuggestedFixes = []analysis.SuggestedFix{*fix}
+				}
+			}
+			pass.Report(diagnostic)
+			missingFileCount += 1
+			continue
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			logger.Warn("failed to read file", "err", err)
+			continue
+		}
+		if len(contents) != fileData.file.Size() {
+			logger.Warn("file was modified during analysis")
+			continue
+		}
+		if !fileData.checkNotHandWritten(pass) {
+			logger.Debug("skipping file with hand-written code")
+			continue
+		}
+		if len(fileData.wanted) == 0 {
+			logger.Debug("generated file is orphaned")
+			// A fix cannot delete a file, so drop all the declarations instead
+			// (including imports, which would otherwise be unused), leaving
+			// only the header which marks the file as safe to remove.
+			var header bytes.Buffer
+			writeFileHeader(&header, pass.Pkg, settings.Header)
+			pass.Report(analysis.Diagnostic{
+				Pos:      fileData.astFile.Package,
+				Category: diagnosticCategoryOrphaned,
+				Message: fmt.Sprintf("orphaned generated file %s has no matching queries; run querygen -write to remove it",
+					filepath.Base(path)),
+				SuggestedFixes: []analysis.SuggestedFix{{
+					Message:   "Remove generated query vars",
+					TextEdits: []analysis.TextEdit{fileData.replaceAllEdit(header.Bytes())},
+				}},
+			})
+			orphanedFileCount += 1
+			continue
+		}
+		formattedBytes, err := fileData.generateFile(pass.Pkg, pass.Fset, contents, settings)
+		if err != nil {
+			logger.Error("failed to generate file", "err", err)
+			continue
+		}
+		if !bytes.Equal(contents, formattedBytes) {
+			logger.Debug("generated file is stale")
+			// Point at the query whose vars changed, if any,
+			// rather than at whichever query comes first.
+			pos := fileData.astFile.Package
+			if goStruct := fileData.firstStaleStruct(contents, formattedBytes); goStruct != nil {
+				pos = goStruct.QueryConst.Pos()
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:      pos,
+				Category: diagnosticCategoryStale,
+				Message:  fmt.Sprintf("stale generated file %s; run querygen -write to update it", filepath.Base(path)),
+				SuggestedFixes: []analysis.SuggestedFix{{
+					Message:   "Regenerate query vars",
+					TextEdits: []analysis.TextEdit{fileData.replaceAllEdit(formattedBytes)},
+				}},
+			})
+			staleFileCount += 1
+		}
+	}
+
+	if staleFileCount != 0 || missingFileCount != 0 || orphanedFileCount != 0 {
+		logger.Info("querygen check summary",
+			"pkg", pass.Pkg.Path(),
+			"filesMissing", missingFileCount,
+			"filesOutOfDate", staleFileCount,
+			"filesOrphaned", orphanedFileCount)
+	}
+}
+
+func updateQueryGenFiles(logger *log.Logger, pass *analysis.Pass, settings *internal.Settings, queryGenFiles map[string]*queryGenFileData) {
+	pkg, fset := pass.Pkg, pass.Fset
+
+	createdFileCount := 0
+	updatedFileCount := 0
+	removedFileCount := 0
+
+	// All the possibilities:
+	//
+	//	| a.go    | Num queries | a_query_gen.go | Action                     |
+	//	|---------|-------------|----------------|----------------------------|
+	//	| Present | 1+          | Up-to-date     | Update file (no-op)        |
+	//	| Present | 1+          | Stale          | Update file (creates diff) |
+	//	| Present | 1+          | Absent         | Create file                |
+	//	| Present | 0           | Present        | Remove file (if generated) |
+	//	| Absent  | n/a         | Present        | Remove file (if generated) |
+	//
+	// Existing files are only modified or removed if they start with
+	// generatedFileHeader and only contain generated declarations,
+	// so that hand-written code is never lost.
+	for path, fileData := range queryGenFiles {
+		logger := logger.With("path", path)
+		if !claimPath(path) {
+			logger.Debug("file already handled for another variant of the package")
+			continue
+		}
+		// Handle the creation case
+		if fileData.file == nil {
+			logger.Debug("creating new file")
+			if err := fileData.createNewFile(pkg, fset, path, settings); err != nil {
+				logger.Error("failed to write generated structs", "err", err)
+			} else {
+				createdFileCount += 1
+			}
+			continue
+		}
+		if !fileData.checkNotHandWritten(pass) {
+			logger.Warn("skipping file with hand-written code")
+			continue
+		}
+		// Handle both removal cases
+		if len(fileData.wanted) == 0 {
+			logger.Debug("removing file")
+			removed, err := removeQueryGenFile(path)
+			if err != nil {
+				logger.Warn("failed to remove file", "err", err)
+			} else if !removed {
+				logger.Warn("not removing orphaned file without querygen header")
+			} else {
+				removedFileCount += 1
+			}
+			continue
+		}
+		// Handle the update case
+		logger.Debug("updating file")
+		modified, err := fileData.updateFile(pkg, fset, settings)
+		if err != nil {
+			logger.Warn("failed to update file", "err", err)
+		} else if modified {
+			updatedFileCount += 1
+		}
+	}
+
+	if createdFileCount != 0 || updatedFileCount != 0 || removedFileCount != 0 {
+		logger.Info("querygen codegen summary",
+			"pkg", pkg.Path(),
+			"filesCreated", createdFileCount,
+			"filesUpdated", updatedFileCount,
+			"filesRemoved", removedFileCount)
+	}
+}
+
+func gatherQueryGenFileData(logger *log.Logger, pass *analysis.Pass, settings *internal.Settings) (map[string]*queryGenFileData, []analysisResult) {
+	p := pool.NewWithResults[analysisResult]()
+	// Files are visited concurrently, but Pass.Report is not thread-safe.
+	var reportMu sync.Mutex
+	syncPass := *pass
+	syncPass.Report = func(diagnostic analysis.Diagnostic) {
+		reportMu.Lock()
+		defer reportMu.Unlock()
+		pass.Report(diagnostic)
+	}
+	queryGenFiles := map[string]*queryGenFileData{}
+	nonGenFilePaths := internal.Set[string]{}
+	for _, astFile := range pass.Files {
+		file := pass.Fset.File(astFile.FileStart)
+		if file == nil { // malformed code/bug
+			continue
+		}
+		logger := logger.With("path", file.Name())
+		if isQueryGenFilePath(file.Name(), settings.GeneratedFileSuffix) {
+			logger.Debug("not visiting file")
+			queryGenFiles[file.Name()] = &queryGenFileData{file, astFile, nil}
+			continue
+		}
+		nonGenFilePaths.Add(file.Name())
+		p.Go(func() analysisResult {
+			logger.Debug("visiting file")
+			visitor := internal.NewQueryGenVisitor(logger, &syncPass, settings)
+			ast.Walk(visitor, astFile)
+			return analysisResult{file, astFile, visitor.ParamStructs}
+		})
+	}
+	results := p.Wait()
+	return queryGenFiles, results
+}
+
+// getQueryGenFilename returns the path of the generated file for
+// the source file at original, given the configured suffix
+// (such as "_query_gen").
+func getQueryGenFilename(original string, suffix string) string {
+	if strings.HasSuffix(original, "_query.go") {
+		return original[:len(original)-len("_query.go")] + suffix + ".go"
+	}
+	if strings.HasSuffix(original, "_queries.go") {
+		return original[:len(original)-len("_queries.go")] + suffix + ".go"
+	}
+	if strings.HasSuffix(original, "_test.go") && !strings.HasSuffix(original, suffix+"_test.go") {
+		return original[:len(original)-len("_test.go")] + suffix + "_test.go"
+	}
+	return original[:len(original)-len(".go")] + suffix + ".go"
+}
+
+func isQueryGenFilePath(p string, suffix string) bool {
+	return strings.HasSuffix(p, suffix+".go") || strings.HasSuffix(p, suffix+"_test.go")
+}
+
+// generatedFileHeader marks generated files. It is the first line of
+// generated files, unless a custom header is configured, in which case
+// it follows that header.
+const generatedFileHeader = "// Code generated by querygen."
+
+// hasGeneratedFileHeader reports whether file was written by querygen.
+// Only comments such as a custom header or build constraints may precede
+// generatedFileHeader.
+func hasGeneratedFileHeader(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if comment.Text == generatedFileHeader {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// removeQueryGenFile removes the generated file at path, unless it
+// doesn't have the header written by querygen.
+func removeQueryGenFile(path string) (removed bool, _ error) {
+	err := withFileLock(path, func() error {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err, "failed to read query gen file")
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, contents, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil || !hasGeneratedFileHeader(file) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed = true
+		return nil
+	})
+	return removed, err
+}
+
+// writeFileHeader writes everything up to and including the package clause,
+// starting with the custom header text, if any.
+func writeFileHeader(buf *bytes.Buffer, pkg *types.Package, header string) {
+	if header = strings.TrimRight(header, "\n"); header != "" {
+		for _, line := range strings.Split(header, "\n") {
+			buf.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString(generatedFileHeader + "\n")
+	buf.WriteString("// You may only edit import statements.\n")
+	buf.WriteString(fmt.Sprintf("package %s\n", pkg.Name()))
+}
+
+// checkNotHandWritten reports a diagnostic and returns false if the existing
+// file wasn't generated by querygen, or has hand-written declarations
+// which would be lost when regenerating it.
+func (fileData *queryGenFileData) checkNotHandWritten(pass *analysis.Pass) bool {
+	astFile := fileData.astFile
+	if !hasGeneratedFileHeader(astFile) {
+		pass.Report(analysis.Diagnostic{
+			Pos:      astFile.Package,
+			Category: diagnosticCategoryHandWritten,
+			Message: fmt.Sprintf("%s was not generated by querygen since it doesn't have the %q header; "+
+				"rename it so that querygen doesn't overwrite it", filepath.Base(fileData.file.Name()), generatedFileHeader),
+		})
+		return false
+	}
+	if decl := internal.FindHandWrittenDecl(astFile); decl != nil {
+		pass.Report(analysis.Diagnostic{
+			Pos:      decl.Pos(),
+			End:      decl.End(),
+			Category: diagnosticCategoryHandWritten,
+			Message: fmt.Sprintf("hand-written declaration in generated file %s; "+
+				"move it to another file so that querygen can update this one", filepath.Base(fileData.file.Name())),
+		})
+		return false
+	}
+	return true
+}
+
+// replaceAllEdit returns a TextEdit replacing the entire contents of an
+// existing generated file.
+// createFileFix creates the missing generated file at path with just
+// the header, returning a fix which fills in the rest. Returns nil if
+// the file already exists, e.g. if it was created for another variant
+// of the package.
+func (fileData *queryGenFileData) createFileFix(pass *analysis.Pass, path string, settings *internal.Settings) (*analysis.SuggestedFix, error) {
+	formattedBytes, err := fileData.generateFile(pass.Pkg, pass.Fset, nil, settings)
+	if err != nil {
+		return nil, err
+	}
+	var header bytes.Buffer
+	writeFileHeader(&header, pass.Pkg, settings.Header)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Write(header.Bytes())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	file := pass.Fset.AddFile(path, -1, header.Len())
+	file.SetLinesForContent(header.Bytes())
+	return &analysis.SuggestedFix{
+		Message: "Create generated query vars",
+		TextEdits: []analysis.TextEdit{{
+			Pos:     file.Pos(0),
+			End:     file.Pos(file.Size()),
+			NewText: formattedBytes,
+		}},
+	}, nil
+}
+
+func (fileData *queryGenFileData) replaceAllEdit(newText []byte) analysis.TextEdit {
+	return analysis.TextEdit{
+		Pos:     fileData.file.Pos(0),
+		End:     fileData.file.Pos(fileData.file.Size()),
+		NewText: newText,
+	}
+}
+
+// firstStaleStruct returns the first wanted struct whose generated
+// declarations differ between the existing contents and formattedBytes,
+// or nil if only other parts of the file, such as imports, differ.
+func (fileData *queryGenFileData) firstStaleStruct(contents, formattedBytes []byte) *internal.GoStruct {
+	fset := token.NewFileSet()
+	formattedFile, err := parser.ParseFile(fset, fileData.file.Name(), formattedBytes, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+	existingDecls := structDeclTexts(fileData.file, fileData.astFile, contents)
+	formattedDecls := structDeclTexts(fset.File(formattedFile.FileStart), formattedFile, formattedBytes)
+	for i, goStruct := range fileData.wanted {
+		if existingDecls[goStruct.TypeName] != formattedDecls[goStruct.TypeName] {
+			return &fileData.wanted[i]
+		}
+	}
+	return nil
+}
+
+// structDeclTexts returns the source text of the generated declarations
+// in astFile, including their doc comments, by struct name.
+func structDeclTexts(file *token.File, astFile *ast.File, contents []byte) map[string]string {
+	texts := map[string]string{}
+	for _, decl := range astFile.Decls {
+		name, ok := internal.GeneratedDeclStructName(decl)
+		if !ok {
+			continue
+		}
+		start := decl.Pos()
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Doc != nil {
+				start = decl.Doc.Pos()
+			}
+		case *ast.FuncDecl:
+			if decl.Doc != nil {
+				start = decl.Doc.Pos()
+			}
+		}
+		texts[name] += string(contents[file.Offset(start):file.Offset(decl.End())]) + "\n"
+	}
+	return texts
+}
+
+// generateFile returns the formatted contents of the generated file.
+//
+// existingContents are the current contents of the file, if it exists.
+// Imports needed by the generated code are added automatically.
+// Existing imports are kept verbatim (including comments) if they're
+// still needed, or if they are blank or dot imports which may have
+// been added by hand for their side effects.
+func (fileData *queryGenFileData) generateFile(pkg *types.Package, fset *token.FileSet, existingContents []byte, settings *internal.Settings) ([]byte, error) {
+	var buf bytes.Buffer
+	writeFileHeader(&buf, pkg, settings.Header)
+	buf.WriteRune('\n')
+
+	runtimeName := settings.RuntimeQualifier(pkg)
+	requiredByPath := map[string]internal.ImportSpec{}
+	if runtimeName != "" {
+		requiredByPath[settings.RuntimePackage] = internal.ImportSpec{
+			Name: runtimeName, Path: settings.RuntimePackage, PkgName: runtimeName,
+		}
+	}
+	for _, goStruct := range fileData.wanted {
+		for _, field := range goStruct.Fields {
+			for _, spec := range field.Imports {
+				if _, ok := requiredByPath[spec.Path]; !ok {
+					requiredByPath[spec.Path] = spec
+				}
+			}
+		}
+	}
+
+	// As with goimports, standard library imports are grouped
+	// separately from the others.
+	var stdlibImports, otherImports []string
+	addImport := func(path, line string) {
+		if isStandardLibraryPath(path) {
+			stdlibImports = append(stdlibImports, line)
+		} else {
+			otherImports = append(otherImports, line)
+		}
+	}
+	if fileData.astFile != nil {
+		for _, spec := range fileData.astFile.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			keep := false
+			if spec.Name != nil && (spec.Name.Name == "_" || spec.Name.Name == ".") {
+				keep = true
+			} else if required, ok := requiredByPath[path]; ok {
+				localName := required.PkgName
+				if spec.Name != nil {
+					localName = spec.Name.Name
+				}
+				if localName == required.Name {
+					keep = true
+					delete(requiredByPath, path)
+				}
+			}
+			if keep {
+				start, end := spec.Pos(), spec.End()
+				if spec.Doc != nil {
+					start = spec.Doc.Pos()
+				}
+				if spec.Comment != nil {
+					end = spec.Comment.End()
+				}
+				addImport(path, string(existingContents[fset.Position(start).Offset:fset.Position(end).Offset]))
+			}
+		}
+	}
+	var missingPaths []string
+	for path := range requiredByPath {
+		missingPaths = append(missingPaths, path)
+	}
+	sort.Strings(missingPaths)
+	for _, path := range missingPaths {
+		spec := requiredByPath[path]
+		if spec.Name == spec.PkgName {
+			addImport(path, strconv.Quote(path))
+		} else {
+			addImport(path, spec.Name+" "+strconv.Quote(path))
+		}
+	}
+
+	if len(stdlibImports) != 0 || len(otherImports) != 0 {
+		buf.WriteString("import (\n")
+		for _, line := range stdlibImports {
+			buf.WriteString("\t" + line + "\n")
+		}
+		if len(stdlibImports) != 0 && len(otherImports) != 0 {
+			buf.WriteString("\n")
+		}
+		for _, line := range otherImports {
+			buf.WriteString("\t" + line + "\n")
+		}
+		buf.WriteString(")\n\n")
+	}
+
+	internal.WriteStructs(fileData.wanted, &buf, runtimeName, settings.ExportedFields)
+	formattedBytes, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to format generated code")
+	}
+	return formattedBytes, nil
+}
+
+// isStandardLibraryPath reports whether path is the import path of
+// a standard library package, which goimports assumes for paths whose
+// first element doesn't contain a dot.
+func isStandardLibraryPath(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+func (fileData *queryGenFileData) createNewFile(pkg *types.Package, fset *token.FileSet, path string, settings *internal.Settings) error {
+	formattedBytes, err := fileData.generateFile(pkg, fset, nil, settings)
+	if err != nil {
+		return err
+	}
+	return withFileLock(path, func() error {
+		return writeFileAtomic(path, formattedBytes)
+	})
+}
+
+func (fileData *queryGenFileData) updateFile(pkg *types.Package, fset *token.FileSet, settings *internal.Settings) (modified bool, _ error) {
+	path := fileData.file.Name()
+	err := withFileLock(path, func() error {
+		fileContents, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err, "failed to read query gen file")
+		}
+		// Existing imports are copied using offsets from analysis.
+		if len(fileContents) != fileData.file.Size() {
+			return errors.New("file was modified during analysis")
+		}
+
+		formattedBytes, err := fileData.generateFile(pkg, fset, fileContents, settings)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(fileContents, formattedBytes) {
+			return nil
+		}
+		modified = true
+		return writeFileAtomic(path, formattedBytes)
+	})
+	return modified, err
+}
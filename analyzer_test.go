@@ -0,0 +1,156 @@
+package querygen
+
+import (
+	"flag"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestCheck(t *testing.T) {
+	// The stale diagnostic is reported for secondQuery, whose vars changed,
+	// and the fixes update the stale file and empty the orphaned one.
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), Analyzer, "check")
+}
+
+func TestDiagnostics(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "conflicts", "modes")
+
+	// Diagnostics for interpolations point at the source of the offending
+	// text, rather than at the start of the line.
+	results := analysistest.Run(t, analysistest.TestData(), Analyzer, "positions")
+	var positions []token.Position
+	for _, result := range results {
+		for _, diagnostic := range result.Diagnostics {
+			positions = append(positions, result.Pass.Fset.Position(diagnostic.Pos))
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Offset < positions[j].Offset })
+	require.Len(t, positions, 3)
+	for i, source := range []string{"{{id int}}", `\x7b\x7bid int}}`, "fragments.RepoFilterQueryFragment"} {
+		contents, err := os.ReadFile(positions[i].Filename)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(string(contents[positions[i].Offset:]), source), positions[i])
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	copyDir(t, analysistest.TestData(), dir)
+	pkgDir := filepath.Join(dir, "src", "write")
+
+	// All analyzed packages are roots.
+	rootPackages.once.Do(func() {})
+	globalWriteMode = true
+	t.Cleanup(func() { globalWriteMode = false })
+	analysistest.Run(t, dir, Analyzer, "write")
+
+	stale, err := os.ReadFile(filepath.Join(pkgDir, "stale_query_gen.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(stale), "\tid int\n")
+	require.FileExists(t, filepath.Join(pkgDir, "missing_query_gen.go"))
+	require.NoFileExists(t, filepath.Join(pkgDir, "orphaned_query_gen.go"))
+	require.FileExists(t, filepath.Join(pkgDir, "handwritten_query_gen.go"))
+
+	// No lock or temporary files are left behind.
+	entries, err := os.ReadDir(pkgDir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	require.ElementsMatch(t, []string{
+		"handwritten_query_gen.go",
+		"missing.go",
+		"missing_query_gen.go",
+		"stale.go",
+		"stale_query_gen.go",
+	}, names)
+
+	// Only the hand-written file is reported afterwards.
+	globalWriteMode = false
+	analysistest.Run(t, dir, Analyzer, "write")
+}
+
+func TestFixMissing(t *testing.T) {
+	dir := t.TempDir()
+	copyDir(t, analysistest.TestData(), dir)
+	path := filepath.Join(dir, "src", "check", "missing_query_gen.go")
+
+	// As with multichecker -fix, for all analyzed packages.
+	rootPackages.once.Do(func() {})
+	if flag.Lookup("fix") == nil {
+		flag.Bool("fix", false, "apply all suggested fixes")
+	}
+	require.NoError(t, flag.Set("fix", "true"))
+	t.Cleanup(func() { _ = flag.Set("fix", "false") })
+	results := analysistest.Run(t, dir, Analyzer, "check")
+
+	// The missing file is created with just the header,
+	// and the fix replaces it with the generated file.
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "// Code generated by querygen.\n// You may only edit import statements.\npackage check\n", string(contents))
+	var edits []analysis.TextEdit
+	for _, result := range results {
+		for _, diagnostic := range result.Diagnostics {
+			if diagnostic.Category == diagnosticCategoryMissing {
+				require.Len(t, diagnostic.SuggestedFixes, 1)
+				edits = append(edits, diagnostic.SuggestedFixes[0].TextEdits...)
+			}
+		}
+	}
+	require.Len(t, edits, 1)
+	require.Equal(t, path, results[0].Pass.Fset.File(edits[0].Pos).Name())
+	require.Equal(t, token.Pos(len(contents)), edits[0].End-edits[0].Pos)
+	require.Contains(t, string(edits[0].NewText), "type missingQueryVars struct")
+}
+
+func TestHasGeneratedFileHeader(t *testing.T) {
+	for source, want := range map[string]bool{
+		"// Code generated by querygen.\npackage a\n":                     true,
+		"//go:build linux\n\n// Code generated by querygen.\npackage a\n": true,
+		"// Copyright.\n// Code generated by querygen.\npackage a\n":      true,
+		"package a\n\n// Code generated by querygen.\n":                   false,
+		"/* Code generated by querygen. */\npackage a\n":                  false,
+		"// Code generated by querygen. DO NOT EDIT.\npackage a\n":        false,
+	} {
+		// removeQueryGenFile uses the same check as the analyzer.
+		path := filepath.Join(t.TempDir(), "a_query_gen.go")
+		require.NoError(t, os.WriteFile(path, []byte(source), 0644))
+		removed, err := removeQueryGenFile(path)
+		require.NoError(t, err)
+		require.Equal(t, want, removed, source)
+	}
+}
+
+// copyDir copies the files in src to dst.
+func copyDir(t *testing.T, src, dst string) {
+	t.Helper()
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, relPath), 0755)
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, relPath), contents, 0644)
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,238 @@
+package internal
+
+import (
+	"bytes"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/regexp"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// ConfigFileName is the name of the configuration file, which is looked up
+// in the root directory of the module being analyzed.
+const ConfigFileName = ".querygen.yaml"
+
+// DefaultRuntimePackage is the package providing the runtime support
+// for generated code.
+const DefaultRuntimePackage = "github.com/sourcegraph/querygen/lib/interpolate"
+
+// Settings are the effective settings for a package directory.
+type Settings struct {
+	// QueryNameRegex matches the names of query constants.
+	QueryNameRegex *regexp.Regexp
+	// GeneratedFileSuffix is appended to the name of a source file
+	// (minus .go or _test.go) to get the name of its generated file.
+	GeneratedFileSuffix string
+	// RuntimePackage is the import path of the package providing the API
+	// used by generated code, as lib/interpolate does: QueryVars, Query,
+	// DoFormat, MustDoFormat, List (with its WithSeparator and OrEmpty
+	// methods), Array, Section, and Fragment, whose values are created
+	// using NewFragment. It is referred to by the last element of its path,
+	// which must be its package name.
+	RuntimePackage string
+	// Header is extra text, such as a license, for the start of generated files.
+	Header string
+	// ExportedFields makes generated struct fields exported,
+	// by upper-casing the first letter of each interpolation name.
+	ExportedFields bool
+	// RequireQueryDirective only treats constants marked with
+	// //querygen:query as queries, ignoring QueryNameRegex.
+	RequireQueryDirective bool
+	// RejectInvalidTypes skips generating structs for queries with
+	// interpolation types that fail to type-check. It is the default;
+	// otherwise, such structs are generated using the types as written,
+	// which don't compile until the types are fixed.
+	RejectInvalidTypes bool
+}
+
+// DefaultSettings returns the settings used without a configuration file.
+func DefaultSettings() Settings {
+	return Settings{
+		QueryNameRegex:      QueryConstNameRegex,
+		GeneratedFileSuffix: "_query_gen",
+		RuntimePackage:      DefaultRuntimePackage,
+		RejectInvalidTypes:  true,
+	}
+}
+
+// RuntimePackageName is the qualifier used for RuntimePackage.
+func (s *Settings) RuntimePackageName() string {
+	return path.Base(s.RuntimePackage)
+}
+
+// RuntimeQualifier returns the qualifier for RuntimePackage in code
+// generated for pkg, or an empty string if pkg is RuntimePackage itself.
+func (s *Settings) RuntimeQualifier(pkg *types.Package) string {
+	if pkg.Path() == s.RuntimePackage {
+		return ""
+	}
+	return s.RuntimePackageName()
+}
+
+// configLayer is a set of settings from the configuration file,
+// where nil fields are inherited.
+type configLayer struct {
+	QueryNamePattern    *string `yaml:"queryNamePattern"`
+	GeneratedFileSuffix *string `yaml:"generatedFileSuffix"`
+	RuntimePackage      *string `yaml:"runtimePackage"`
+	Header              *string `yaml:"header"`
+	ExportedFields      *bool   `yaml:"exportedFields"`
+	Strict              struct {
+		RequireQueryDirective *bool `yaml:"requireQueryDirective"`
+		RejectInvalidTypes    *bool `yaml:"rejectInvalidTypes"`
+	} `yaml:"strict"`
+}
+
+type configOverride struct {
+	// Path is a directory relative to the module root. The override
+	// applies to packages in that directory and its subdirectories.
+	Path        string `yaml:"path"`
+	configLayer `yaml:",inline"`
+}
+
+type configFile struct {
+	configLayer `yaml:",inline"`
+	// Overrides are applied in order, so later ones take precedence.
+	Overrides []configOverride `yaml:"overrides"`
+}
+
+func (layer *configLayer) apply(settings *Settings) error {
+	if layer.QueryNamePattern != nil {
+		re, err := regexp.Compile(*layer.QueryNamePattern)
+		if err != nil {
+			return errors.Wrap(err, "invalid queryNamePattern")
+		}
+		settings.QueryNameRegex = re
+	}
+	if layer.GeneratedFileSuffix != nil {
+		suffix := *layer.GeneratedFileSuffix
+		if suffix == "" || strings.ContainsAny(suffix, `/\`) || strings.HasSuffix(suffix, "_test") {
+			return errors.Newf("invalid generatedFileSuffix %q", suffix)
+		}
+		settings.GeneratedFileSuffix = suffix
+	}
+	if layer.RuntimePackage != nil {
+		if *layer.RuntimePackage == "" {
+			return errors.New("runtimePackage must not be empty")
+		}
+		settings.RuntimePackage = *layer.RuntimePackage
+	}
+	if layer.Header != nil {
+		settings.Header = *layer.Header
+	}
+	if layer.ExportedFields != nil {
+		settings.ExportedFields = *layer.ExportedFields
+	}
+	if layer.Strict.RequireQueryDirective != nil {
+		settings.RequireQueryDirective = *layer.Strict.RequireQueryDirective
+	}
+	if layer.Strict.RejectInvalidTypes != nil {
+		settings.RejectInvalidTypes = *layer.Strict.RejectInvalidTypes
+	}
+	return nil
+}
+
+// configCache holds the parsed configuration files, so that they aren't
+// parsed again for every package. Entries are keyed by the path of the
+// file, and are only used while its modification time and size match,
+// since long-running drivers like gopls outlive edits to the file.
+var configCache struct {
+	sync.Mutex
+	files map[string]cachedConfigFile
+}
+
+type cachedConfigFile struct {
+	modTime time.Time
+	size    int64
+	config  *configFile
+}
+
+// LoadSettings returns the settings for the package in dir, based on
+// the configuration file in the root of the enclosing module, if any.
+func LoadSettings(dir string) (Settings, error) {
+	settings := DefaultSettings()
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return settings, err
+	}
+	moduleRoot := findModuleRoot(dir)
+	if moduleRoot == "" {
+		return settings, nil
+	}
+	config, err := loadConfigFile(moduleRoot)
+	if err != nil || config == nil {
+		return settings, err
+	}
+	configPath := filepath.Join(moduleRoot, ConfigFileName)
+	if err := config.apply(&settings); err != nil {
+		return settings, errors.Wrap(err, configPath)
+	}
+	relDir, err := filepath.Rel(moduleRoot, dir)
+	if err != nil {
+		return settings, err
+	}
+	relDir = filepath.ToSlash(relDir)
+	for _, override := range config.Overrides {
+		overridePath := path.Clean(override.Path)
+		if overridePath == "." || relDir == overridePath || strings.HasPrefix(relDir, overridePath+"/") {
+			if err := override.apply(&settings); err != nil {
+				return settings, errors.Wrapf(err, "%s: override for %s", configPath, override.Path)
+			}
+		}
+	}
+	return settings, nil
+}
+
+func findModuleRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadConfigFile returns the configuration file in moduleRoot,
+// or nil if there is none.
+func loadConfigFile(moduleRoot string) (*configFile, error) {
+	configPath := filepath.Join(moduleRoot, ConfigFileName)
+	info, err := os.Stat(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	configCache.Lock()
+	defer configCache.Unlock()
+	if cached, ok := configCache.files[configPath]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.config, nil
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	config := &configFile{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", configPath)
+	}
+	if configCache.files == nil {
+		configCache.files = map[string]cachedConfigFile{}
+	}
+	configCache.files[configPath] = cachedConfigFile{info.ModTime(), info.Size(), config}
+	return config, nil
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSettings(t *testing.T) {
+	writeModule := func(t *testing.T, config string) string {
+		root := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/m\n"), 0o644))
+		if config != "" {
+			require.NoError(t, os.WriteFile(filepath.Join(root, ConfigFileName), []byte(config), 0o644))
+		}
+		return root
+	}
+
+	t.Run("no config file", func(t *testing.T) {
+		root := writeModule(t, "")
+		settings, err := LoadSettings(filepath.Join(root, "pkg"))
+		require.NoError(t, err)
+		require.Equal(t, DefaultSettings(), settings)
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		root := writeModule(t, `
+queryNamePattern: 'SQL$'
+header: |
+  Copyright Example
+exportedFields: true
+overrides:
+  - path: internal
+    generatedFileSuffix: _sql_gen
+    strict:
+      rejectInvalidTypes: false
+  - path: internal/legacy
+    exportedFields: false
+    runtimePackage: example.com/m/lib/sqlvars
+`)
+		settings, err := LoadSettings(root)
+		require.NoError(t, err)
+		require.Equal(t, "SQL$", settings.QueryNameRegex.String())
+		require.Equal(t, "Copyright Example\n", settings.Header)
+		require.True(t, settings.ExportedFields)
+		require.Equal(t, "_query_gen", settings.GeneratedFileSuffix)
+		require.True(t, settings.RejectInvalidTypes)
+
+		settings, err = LoadSettings(filepath.Join(root, "internal", "store"))
+		require.NoError(t, err)
+		require.Equal(t, "_sql_gen", settings.GeneratedFileSuffix)
+		require.False(t, settings.RejectInvalidTypes)
+		require.True(t, settings.ExportedFields)
+
+		settings, err = LoadSettings(filepath.Join(root, "internal", "legacy"))
+		require.NoError(t, err)
+		require.Equal(t, "_sql_gen", settings.GeneratedFileSuffix)
+		require.False(t, settings.ExportedFields)
+		require.Equal(t, "sqlvars", settings.RuntimePackageName())
+
+		// Paths match whole directory names.
+		settings, err = LoadSettings(filepath.Join(root, "internalx"))
+		require.NoError(t, err)
+		require.Equal(t, "_query_gen", settings.GeneratedFileSuffix)
+	})
+
+	t.Run("changed config file", func(t *testing.T) {
+		root := writeModule(t, "exportedFields: true\n")
+		settings, err := LoadSettings(root)
+		require.NoError(t, err)
+		require.True(t, settings.ExportedFields)
+
+		configPath := filepath.Join(root, ConfigFileName)
+		require.NoError(t, os.WriteFile(configPath, []byte("exportedFields: false\n"), 0o644))
+		settings, err = LoadSettings(root)
+		require.NoError(t, err)
+		require.False(t, settings.ExportedFields)
+
+		require.NoError(t, os.Remove(configPath))
+		settings, err = LoadSettings(root)
+		require.NoError(t, err)
+		require.Equal(t, DefaultSettings(), settings)
+	})
+
+	for _, testCase := range []struct {
+		name   string
+		config string
+	}{
+		{"unknown field", "exportedFeilds: true\n"},
+		{"invalid pattern", "queryNamePattern: '('\n"},
+		{"invalid suffix", "generatedFileSuffix: _gen_test\n"},
+		{"invalid override", "overrides:\n  - path: a\n    runtimePackage: ''\n"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			root := writeModule(t, testCase.config)
+			_, err := LoadSettings(filepath.Join(root, "a"))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+const directivePrefix = "//querygen:"
+
+// QueryDirectives are the //querygen: directives for a const spec.
+//
+// Directives in the doc comment of a const group apply to every spec
+// in the group, in addition to those in the spec's own doc comment.
+type QueryDirectives struct {
+	// Query is set by //querygen:query, to treat a constant as a query
+	// even if its name doesn't match QueryConstNameRegex.
+	Query bool
+	// Ignore is set by //querygen:ignore, to skip a constant
+	// even if its name matches QueryConstNameRegex.
+	Ignore bool
+	// TypeName is set by //querygen:name=TypeName, to override
+	// the name of the generated struct.
+	TypeName string
+	// Pos is the position of the first directive, if any.
+	Pos token.Pos
+}
+
+// ParseQueryDirectives reads the directives for valSpec, reporting
+// malformed ones.
+func ParseQueryDirectives(pass *analysis.Pass, genDecl *ast.GenDecl, valSpec *ast.ValueSpec) QueryDirectives {
+	var directives QueryDirectives
+	for _, doc := range []*ast.CommentGroup{genDecl.Doc, valSpec.Doc} {
+		if doc == nil {
+			continue
+		}
+		isGroupDoc := doc == genDecl.Doc && genDecl.Lparen.IsValid()
+		for _, comment := range doc.List {
+			directive, ok := strings.CutPrefix(comment.Text, directivePrefix)
+			if !ok {
+				continue
+			}
+			if !directives.Pos.IsValid() {
+				directives.Pos = comment.Pos()
+			}
+			directive = strings.TrimSpace(directive)
+			switch {
+			case directive == "query":
+				directives.Query = true
+			case directive == "ignore":
+				directives.Ignore = true
+			case strings.HasPrefix(directive, "name="):
+				typeName := strings.TrimPrefix(directive, "name=")
+				switch {
+				case !token.IsIdentifier(typeName):
+					pass.Reportf(comment.Pos(), "invalid type name %q in %s directive", typeName, comment.Text)
+				case isGroupDoc || len(valSpec.Names) != 1:
+					pass.Reportf(comment.Pos(), "%sname must be attached to a single constant", directivePrefix)
+				default:
+					directives.TypeName = typeName
+				}
+			default:
+				pass.Reportf(comment.Pos(), "unknown directive %s; expected one of %squery, %signore or %sname=TypeName",
+					comment.Text, directivePrefix, directivePrefix, directivePrefix)
+			}
+		}
+	}
+	if directives.Query && directives.Ignore {
+		pass.Reportf(directives.Pos, "conflicting %squery and %signore directives", directivePrefix, directivePrefix)
+		directives.Query = false
+	}
+	return directives
+}
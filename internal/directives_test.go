@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/hexops/autogold/v2"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis"
+)
+
+func TestParseQueryDirectives(t *testing.T) {
+	src := `package p
+
+//querygen:query
+const a = ""
+
+const (
+	//querygen:ignore
+	b = ""
+
+	//querygen:name=CVars
+	c = ""
+
+	//querygen:name=not-an-ident
+	d = ""
+
+	//querygen:query
+	//querygen:ignore
+	e = ""
+
+	//querygen:bogus
+	f, g = "", ""
+
+	//querygen:name=HVars
+	h, i = "", ""
+)
+
+//querygen:name=JVars
+const (
+	j = ""
+)
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	require.NoError(t, err)
+
+	var diagnostics []string
+	pass := &analysis.Pass{Fset: fset, Report: func(diagnostic analysis.Diagnostic) {
+		diagnostics = append(diagnostics, fmt.Sprintf("%d: %s", fset.Position(diagnostic.Pos).Line, diagnostic.Message))
+	}}
+	var results []string
+	for _, decl := range file.Decls {
+		genDecl := decl.(*ast.GenDecl)
+		for _, spec := range genDecl.Specs {
+			valSpec := spec.(*ast.ValueSpec)
+			directives := ParseQueryDirectives(pass, genDecl, valSpec)
+			results = append(results, fmt.Sprintf("%s: query=%v ignore=%v name=%q",
+				valSpec.Names[0].Name, directives.Query, directives.Ignore, directives.TypeName))
+		}
+	}
+
+	autogold.Expect(`a: query=true ignore=false name=""
+b: query=false ignore=true name=""
+c: query=false ignore=false name="CVars"
+d: query=false ignore=false name=""
+e: query=false ignore=true name=""
+f: query=false ignore=false name=""
+h: query=false ignore=false name=""
+j: query=false ignore=false name=""`).Equal(t, strings.Join(results, "\n"))
+	autogold.Expect(`13: invalid type name "not-an-ident" in //querygen:name=not-an-ident directive
+16: conflicting //querygen:query and //querygen:ignore directives
+20: unknown directive //querygen:bogus; expected one of //querygen:query, //querygen:ignore or //querygen:name=TypeName
+23: //querygen:name must be attached to a single constant
+27: //querygen:name must be attached to a single constant`).Equal(t, strings.Join(diagnostics, "\n"))
+}
@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// QueryFact is exported for every query constant with interpolations,
+// so that packages using the constant as a fragment of their own queries
+// know the types of its fields without having to resolve them in a scope
+// where they might not be visible.
+type QueryFact struct {
+	// Text is the folded query text.
+	Text string
+	// Position is the position of the constant's declaration.
+	Position string
+	// TypeName is the name of the Vars type generated for the constant.
+	TypeName string
+	Fields   []QueryFactField
+}
+
+// QueryFactField describes a field of a query from another package.
+type QueryFactField struct {
+	Name string
+	// TypeName is the type of the field, with types from the declaring
+	// package qualified by that package's name, so that it is valid
+	// in any file with the right Imports.
+	TypeName string
+	Imports  []ImportSpec
+	// Spans are the spans of the interpolations for the field in Text.
+	Spans []Span
+	// Error is set if the field cannot be used outside the declaring
+	// package, e.g. because its type is unexported.
+	Error string
+}
+
+var _ analysis.Fact = &QueryFact{}
+
+func (*QueryFact) AFact() {}
+
+func (f *QueryFact) String() string {
+	return fmt.Sprintf("query(%q)", f.Text)
+}
+
+// NewQueryFact creates the fact to export for goStruct.
+func NewQueryFact(pass *analysis.Pass, goStruct *GoStruct) *QueryFact {
+	fact := &QueryFact{
+		Text:     goStruct.Text,
+		Position: pass.Fset.Position(goStruct.QueryConst.Pos()).String(),
+		TypeName: goStruct.TypeName,
+	}
+	for _, field := range goStruct.Fields {
+		factField := QueryFactField{Name: field.Name, TypeName: field.Type.Name, Imports: field.Imports}
+		for _, use := range field.Uses {
+			factField.Spans = append(factField.Spans, use.Span)
+		}
+		typeName, localImport, err := qualifyLocalTypes(pass.Pkg, field.Type.Name)
+		if err != nil {
+			factField.Error = err.Error()
+		} else if localImport != nil {
+			factField.TypeName = typeName
+			factField.Imports = append(factField.Imports, *localImport)
+		}
+		fact.Fields = append(fact.Fields, factField)
+	}
+	return fact
+}
+
+// qualifyLocalTypes qualifies the unqualified references to types
+// declared in pkg in typeName, returning the import needed for them,
+// if any.
+func qualifyLocalTypes(pkg *types.Package, typeName string) (string, *ImportSpec, error) {
+	expr, err := parser.ParseExpr(typeName)
+	if err != nil {
+		return "", nil, err
+	}
+	var localTypeIdents []*ast.Ident
+	ast.Inspect(expr, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.SelectorExpr:
+			// Already qualified
+			return false
+		case *ast.Field:
+			// Skip parameter and field names in func and struct types.
+			if node.Type != nil {
+				ast.Inspect(node.Type, func(node ast.Node) bool {
+					return visitLocalTypeIdents(pkg, node, &localTypeIdents)
+				})
+			}
+			return false
+		}
+		return visitLocalTypeIdents(pkg, node, &localTypeIdents)
+	})
+	if len(localTypeIdents) == 0 {
+		return typeName, nil, nil
+	}
+	for _, ident := range localTypeIdents {
+		if !token.IsExported(ident.Name) {
+			return "", nil, errors.Newf("type %s is not exported by package %s", ident.Name, pkg.Path())
+		}
+		ident.Name = pkg.Name() + "." + ident.Name
+	}
+	return types.ExprString(expr), &ImportSpec{Name: pkg.Name(), Path: pkg.Path(), PkgName: pkg.Name()}, nil
+}
+
+func visitLocalTypeIdents(pkg *types.Package, node ast.Node, idents *[]*ast.Ident) bool {
+	switch node := node.(type) {
+	case *ast.SelectorExpr:
+		return false
+	case *ast.Ident:
+		if _, ok := pkg.Scope().Lookup(node.Name).(*types.TypeName); ok {
+			*idents = append(*idents, node)
+		}
+	}
+	return true
+}
+
+// fieldFromFact converts a field of a query from another package
+// to a field for the query being built, re-qualifying its type
+// based on the imports in scope at pos.
+func fieldFromFact(pass *analysis.Pass, pos token.Pos, fact *QueryFact, factField *QueryFactField) (TypeName, []ImportSpec, types.Type, error) {
+	if factField.Error != "" {
+		return TypeName{}, nil, nil, errors.Newf("field %s of query declared at %s cannot be used here: %s",
+			factField.Name, fact.Position, factField.Error)
+	}
+	expr, err := parser.ParseExpr(factField.TypeName)
+	if err != nil {
+		return TypeName{}, nil, nil, err
+	}
+	importsByName := map[string]ImportSpec{}
+	for _, spec := range factField.Imports {
+		importsByName[spec.Name] = spec
+	}
+
+	// Type-check the type in a synthetic package which only has
+	// the needed imports in scope.
+	scopePkg := types.NewPackage("querygen/fact", "fact")
+	var imports []ImportSpec
+	var missingPath string
+	ast.Inspect(expr, func(node ast.Node) bool {
+		selector, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		qualifier, ok := selector.X.(*ast.Ident)
+		if !ok {
+			return false
+		}
+		spec, ok := importsByName[qualifier.Name]
+		if !ok {
+			return false
+		}
+		localName := spec.PkgName
+		if pkgName := lookupPkgNameForPath(pass, pos, spec.Path); pkgName != nil {
+			localName = pkgName.Name()
+		}
+		if imported := findImportedPackage(pass.Pkg, spec.Path); imported != nil {
+			if scopePkg.Scope().Lookup(localName) == nil {
+				scopePkg.Scope().Insert(types.NewPkgName(token.NoPos, scopePkg, localName, imported))
+			}
+		} else {
+			missingPath = spec.Path
+		}
+		qualifier.Name = localName
+		imports = append(imports, ImportSpec{Name: localName, Path: spec.Path, PkgName: spec.PkgName})
+		return false
+	})
+
+	var resolvedType types.Type
+	if missingPath == "" {
+		resolvedType, _ = checkTypeExpr(pass.Fset, scopePkg, token.NoPos, expr)
+	}
+	return TypeName{types.ExprString(expr)}, imports, resolvedType, nil
+}
+
+// lookupPkgNameForPath finds the local name of the import of path
+// in the file containing pos.
+func lookupPkgNameForPath(pass *analysis.Pass, pos token.Pos, path string) *types.PkgName {
+	for _, file := range pass.Files {
+		if !(file.FileStart <= pos && pos < file.FileEnd) {
+			continue
+		}
+		for _, spec := range file.Imports {
+			if spec.Name != nil && (spec.Name.Name == "_" || spec.Name.Name == ".") {
+				continue
+			}
+			var obj types.Object
+			if spec.Name != nil {
+				obj = pass.TypesInfo.Defs[spec.Name]
+			} else {
+				obj = pass.TypesInfo.Implicits[spec]
+			}
+			if pkgName, ok := obj.(*types.PkgName); ok && pkgName.Imported().Path() == path {
+				return pkgName
+			}
+		}
+	}
+	return nil
+}
+
+// findImportedPackage finds the package with the given path
+// among the transitive imports of pkg.
+func findImportedPackage(pkg *types.Package, path string) *types.Package {
+	seen := Set[*types.Package]{}
+	var visit func(pkg *types.Package) *types.Package
+	visit = func(pkg *types.Package) *types.Package {
+		if seen.Has(pkg) {
+			return nil
+		}
+		seen.Add(pkg)
+		if pkg.Path() == path {
+			return pkg
+		}
+		for _, imported := range pkg.Imports() {
+			if found := visit(imported); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return visit(pkg)
+}
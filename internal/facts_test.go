@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQualifyLocalTypes(t *testing.T) {
+	pkg := types.NewPackage("example.com/shared", "shared")
+	for _, name := range []string{"RepoID", "cursor"} {
+		typeName := types.NewTypeName(token.NoPos, pkg, name, nil)
+		types.NewNamed(typeName, types.Typ[types.Int], nil)
+		pkg.Scope().Insert(typeName)
+	}
+	sharedImport := &ImportSpec{Name: "shared", Path: "example.com/shared", PkgName: "shared"}
+
+	testCases := []struct {
+		typeName   string
+		want       string
+		wantImport *ImportSpec
+		wantErr    bool
+	}{
+		{typeName: "int", want: "int"},
+		{typeName: "time.Time", want: "time.Time"},
+		{typeName: "RepoID", want: "shared.RepoID", wantImport: sharedImport},
+		{typeName: "[]*RepoID", want: "[]*shared.RepoID", wantImport: sharedImport},
+		{typeName: "map[RepoID]other.RepoID", want: "map[shared.RepoID]other.RepoID", wantImport: sharedImport},
+		{typeName: "func(RepoID int) RepoID", want: "func(RepoID int) shared.RepoID", wantImport: sharedImport},
+		{typeName: "cursor", wantErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.typeName, func(t *testing.T) {
+			got, gotImport, err := qualifyLocalTypes(pkg, testCase.typeName)
+			if testCase.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.want, got)
+			require.Equal(t, testCase.wantImport, gotImport)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// FoldedString is the result of constant-folding a string expression,
+// along with a mapping from byte offsets in Text back to positions
+// in the string literals the text came from.
+type FoldedString struct {
+	Text string
+	// runs is sorted by textOffset. Within a run, offsets map linearly
+	// to positions. A new run starts at every literal and around every
+	// escape sequence, whose length differs in the source.
+	runs []posRun
+}
+
+type posRun struct {
+	textOffset int
+	pos        token.Pos
+	// opaque is set for text without a source literal in this package,
+	// such as a constant imported from another package. All offsets
+	// in the run map to pos, the start of the expression.
+	opaque bool
+	// fact is set if the run is the text of a query from another
+	// package. It is only set for opaque runs.
+	fact *QueryFact
+}
+
+// Pos returns the source position for the byte at offset in f.Text.
+//
+// offset may be len(f.Text), which maps to just after the last byte.
+func (f FoldedString) Pos(offset int) token.Pos {
+	if len(f.runs) == 0 {
+		return token.NoPos
+	}
+	i := sort.Search(len(f.runs), func(i int) bool {
+		return f.runs[i].textOffset > offset
+	}) - 1
+	if i < 0 {
+		return token.NoPos
+	}
+	run := f.runs[i]
+	if run.opaque {
+		return run.pos
+	}
+	return run.pos + token.Pos(offset-run.textOffset)
+}
+
+// Concat returns the folded string for f + other.
+func (f FoldedString) Concat(other FoldedString) FoldedString {
+	runs := make([]posRun, 0, len(f.runs)+len(other.runs))
+	runs = append(runs, f.runs...)
+	for _, run := range other.runs {
+		runs = append(runs, posRun{run.textOffset + len(f.Text), run.pos, run.opaque, run.fact})
+	}
+	return FoldedString{f.Text + other.Text, runs}
+}
+
+// FoldOpaque returns a folded string for text whose individual bytes
+// cannot be mapped back to the source, attributing all of it to pos.
+func FoldOpaque(text string, pos token.Pos) FoldedString {
+	return FoldedString{text, []posRun{{0, pos, true, nil}}}
+}
+
+// FoldImportedQuery returns a folded string for a query constant from
+// another package, attributing all of it to pos.
+func FoldImportedQuery(fact *QueryFact, pos token.Pos) FoldedString {
+	return FoldedString{fact.Text, []posRun{{0, pos, true, fact}}}
+}
+
+// FactAt returns the fact for the imported query containing offset,
+// and the offset in f.Text at which the imported query's text starts.
+func (f FoldedString) FactAt(offset int) (*QueryFact, int) {
+	i := sort.Search(len(f.runs), func(i int) bool {
+		return f.runs[i].textOffset > offset
+	}) - 1
+	if i < 0 || f.runs[i].fact == nil {
+		return nil, 0
+	}
+	return f.runs[i].fact, f.runs[i].textOffset
+}
+
+// FoldStringLit unquotes a string literal from file, keeping track of
+// where each byte of the value came from.
+func FoldStringLit(file *token.File, lit *ast.BasicLit) (FoldedString, error) {
+	if lit.Kind != token.STRING || len(lit.Value) < 2 {
+		return FoldedString{}, errors.New("not a string literal")
+	}
+	var buf strings.Builder
+	var runs []posRun
+	// Length of the opening quote
+	contentStart := 1
+	content := lit.Value[contentStart : len(lit.Value)-1]
+	startRun := func(sourceOffset int) {
+		runs = append(runs, posRun{buf.Len(), lit.ValuePos + token.Pos(contentStart+sourceOffset), false, nil})
+	}
+
+	if lit.Value[0] == '`' {
+		// Raw string literals are used as-is, except that carriage returns
+		// are discarded. The scanner already strips them from lit.Value,
+		// so resynchronize with the file at the start of every line.
+		startRun(0)
+		line := file.Line(lit.ValuePos)
+		for i := 0; i < len(content); i++ {
+			if content[i] == '\r' {
+				startRun(i + 1)
+				continue
+			}
+			buf.WriteByte(content[i])
+			if content[i] == '\n' && line < file.LineCount() {
+				line += 1
+				runs = append(runs, posRun{buf.Len(), file.LineStart(line), false, nil})
+			}
+		}
+		return FoldedString{buf.String(), runs}, nil
+	}
+
+	if lit.Value[0] != '"' {
+		return FoldedString{}, errors.Newf("unexpected string literal %s", lit.Value)
+	}
+	startRun(0)
+	rest := content
+	for len(rest) > 0 {
+		sourceOffset := len(content) - len(rest)
+		isEscape := rest[0] == '\\'
+		if isEscape {
+			startRun(sourceOffset)
+		}
+		value, multibyte, tail, err := strconv.UnquoteChar(rest, '"')
+		if err != nil {
+			return FoldedString{}, errors.Wrapf(err, "invalid string literal %s", lit.Value)
+		}
+		if value < utf8.RuneSelf || !multibyte {
+			buf.WriteByte(byte(value))
+		} else {
+			buf.WriteRune(value)
+		}
+		rest = tail
+		if isEscape && len(rest) > 0 {
+			startRun(len(content) - len(rest))
+		}
+	}
+	return FoldedString{buf.String(), runs}, nil
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFoldStringLit(t *testing.T) {
+	src := "package p\n" +
+		"const a = `SELECT\r\n{{x: int}}`\n" +
+		"const b = \"\\tWHERE y = \\x7b\\x7by: string}} AND \\u00e9 = {{z: int}}\"\n"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	require.NoError(t, err)
+
+	var folded []FoldedString
+	ast.Inspect(file, func(node ast.Node) bool {
+		if lit, ok := node.(*ast.BasicLit); ok {
+			f, err := FoldStringLit(fset.File(lit.Pos()), lit)
+			require.NoError(t, err)
+			folded = append(folded, f)
+		}
+		return true
+	})
+	require.Len(t, folded, 2)
+	require.Equal(t, "SELECT\n{{x: int}}", folded[0].Text)
+	require.Equal(t, "\tWHERE y = {{y: string}} AND é = {{z: int}}", folded[1].Text)
+
+	// Maps a folded text offset to the source text starting at that position.
+	sourceAt := func(f FoldedString, offset int) string {
+		return src[fset.Position(f.Pos(offset)).Offset:]
+	}
+
+	combined := folded[0].Concat(folded[1])
+	require.Equal(t, folded[0].Text+folded[1].Text, combined.Text)
+
+	x := strings.Index(combined.Text, "{{x")
+	require.True(t, strings.HasPrefix(sourceAt(combined, x), "{{x: int}}`"))
+	y := strings.Index(combined.Text, "{{y")
+	require.True(t, strings.HasPrefix(sourceAt(combined, y), `\x7b\x7by: string}}`))
+	require.True(t, strings.HasPrefix(sourceAt(combined, y+2), "y: string}}"))
+	z := strings.Index(combined.Text, "{{z")
+	require.True(t, strings.HasPrefix(sourceAt(combined, z), `{{z: int}}"`))
+	require.True(t, strings.HasPrefix(sourceAt(combined, len(combined.Text)), `"`))
+}
+
+func TestFoldOpaque(t *testing.T) {
+	src := "package p\nconst a = `SELECT ` + other.Fragment\n"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	require.NoError(t, err)
+
+	binaryExpr := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0].(*ast.BinaryExpr)
+	lit, err := FoldStringLit(fset.File(binaryExpr.X.Pos()), binaryExpr.X.(*ast.BasicLit))
+	require.NoError(t, err)
+	combined := lit.Concat(FoldOpaque("x = {{x: int}}", binaryExpr.Y.Pos()))
+	require.Equal(t, "SELECT x = {{x: int}}", combined.Text)
+
+	// All offsets in opaque text map to the start of the expression.
+	for _, offset := range []int{len("SELECT "), strings.Index(combined.Text, "{{"), len(combined.Text)} {
+		require.Equal(t, binaryExpr.Y.Pos(), combined.Pos(offset))
+	}
+	require.Equal(t, binaryExpr.X.Pos()+1, combined.Pos(0))
+}
+
+func TestFoldStringLitUnquote(t *testing.T) {
+	// The folded text must be the runtime value of the literal,
+	// which is what interpolate.Do sees.
+	for _, literal := range []string{
+		"`SELECT {{x: int}}`",
+		"`a\\tb \\x7b\\x7b \"c\"`",
+		"`line\r\nbreak\n`",
+		`"SELECT {{x: int}}"`,
+		`"\t\n\r\a\b\f\v\\\""`,
+		`"\x7b\x7bx: int}} \173\173y: int}}"`,
+		`"\u00e9 \U0001F600 é 😀"`,
+		`"\xff\xfe \377 invalid UTF-8"`,
+		`"\x00 NUL"`,
+		`""`,
+		"``",
+	} {
+		t.Run(literal, func(t *testing.T) {
+			src := "package p\nconst a = " + literal + "\n"
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "p.go", src, 0)
+			require.NoError(t, err)
+			lit := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0].(*ast.BasicLit)
+
+			want, err := strconv.Unquote(lit.Value)
+			require.NoError(t, err)
+			folded, err := FoldStringLit(fset.File(lit.Pos()), lit)
+			require.NoError(t, err)
+			require.Equal(t, want, folded.Text)
+		})
+	}
+}
+
+func FuzzFoldStringLit(f *testing.F) {
+	f.Add("SELECT {{x: int}}")
+	f.Add("tab\there \"quoted\" é \x00  ")
+	f.Add("multi\r\nline\n`raw`")
+	f.Add("\xff\xfe \\x7b")
+	f.Fuzz(func(t *testing.T, value string) {
+		literals := []string{strconv.Quote(value), strconv.QuoteToASCII(value)}
+		if strconv.CanBackquote(value) {
+			literals = append(literals, "`"+value+"`")
+		}
+		for _, literal := range literals {
+			src := "package p\nconst a = " + literal + "\n"
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "p.go", src, 0)
+			require.NoError(t, err)
+			lit := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0].(*ast.BasicLit)
+
+			folded, err := FoldStringLit(fset.File(lit.Pos()), lit)
+			require.NoError(t, err)
+			require.Equal(t, value, folded.Text)
+			// Every offset maps into the literal.
+			for offset := 0; offset <= len(folded.Text); offset++ {
+				pos := folded.Pos(offset)
+				require.True(t, lit.Pos() < pos && pos < lit.End(), "offset %d of %s", offset, literal)
+			}
+		}
+	})
+}
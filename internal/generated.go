@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// FindHandWrittenDecl returns the first declaration in an existing
+// generated file which doesn't have the shape of something written by
+// WriteStructs, or nil if there is no such declaration.
+//
+// Imports are not checked since users may edit them. The check is
+// structural rather than an exact comparison, so that stale generated
+// files (e.g. for a renamed query) can still be updated.
+func FindHandWrittenDecl(file *ast.File) ast.Decl {
+	// Every generated struct has a matching QueryVars assertion.
+	declaredStructs := Set[string]{}
+	for _, decl := range file.Decls {
+		if name, ok := generatedStructName(decl); ok {
+			declaredStructs.Add(name)
+		}
+	}
+	structNames := Set[string]{}
+	for _, decl := range file.Decls {
+		if name, ok := queryVarsAssertionType(decl); ok && declaredStructs.Has(name) {
+			structNames.Add(name)
+		}
+	}
+	for _, decl := range file.Decls {
+		if !isGeneratedDecl(decl, structNames) {
+			return decl
+		}
+	}
+	return nil
+}
+
+func isGeneratedDecl(decl ast.Decl, structNames Set[string]) bool {
+	switch decl := decl.(type) {
+	case *ast.GenDecl:
+		switch decl.Tok {
+		case token.IMPORT:
+			return true
+		case token.TYPE:
+			name, ok := generatedStructName(decl)
+			return ok && structNames.Has(name)
+		case token.VAR:
+			name, ok := queryVarsAssertionType(decl)
+			return ok && structNames.Has(name)
+		case token.CONST:
+			return isFormatConstDecl(decl, structNames)
+		}
+		return false
+	case *ast.FuncDecl:
+		name, ok := generatedMethodRecvName(decl)
+		return ok && structNames.Has(name)
+	default:
+		return false
+	}
+}
+
+// GeneratedDeclStructName returns the name of the struct which decl
+// belongs to, if decl has the shape of something written by WriteStructs.
+func GeneratedDeclStructName(decl ast.Decl) (string, bool) {
+	switch decl := decl.(type) {
+	case *ast.GenDecl:
+		switch decl.Tok {
+		case token.TYPE:
+			return generatedStructName(decl)
+		case token.VAR:
+			return queryVarsAssertionType(decl)
+		case token.CONST:
+			return formatConstStructName(decl)
+		}
+	case *ast.FuncDecl:
+		return generatedMethodRecvName(decl)
+	}
+	return "", false
+}
+
+// generatedMethodRecvName returns the name of T for the generated
+// methods of *T, such as 'func (qp *T) FormatArgs() []any'.
+func generatedMethodRecvName(decl *ast.FuncDecl) (string, bool) {
+	if decl.Recv == nil || len(decl.Recv.List) != 1 {
+		return "", false
+	}
+	switch decl.Name.Name {
+	case "FormatArgs", "Build", "MustBuild":
+	default:
+		return "", false
+	}
+	star, ok := decl.Recv.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return "", false
+	}
+	recvType, ok := star.X.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	return recvType.Name, true
+}
+
+// generatedStructName returns the name of the struct declared by
+// 'type T struct { ... }'.
+func generatedStructName(decl ast.Decl) (string, bool) {
+	genDecl, ok := decl.(*ast.GenDecl)
+	if !ok || genDecl.Tok != token.TYPE || genDecl.Lparen.IsValid() || len(genDecl.Specs) != 1 {
+		return "", false
+	}
+	typeSpec := genDecl.Specs[0].(*ast.TypeSpec)
+	if typeSpec.TypeParams != nil || typeSpec.Assign.IsValid() {
+		return "", false
+	}
+	if _, ok := typeSpec.Type.(*ast.StructType); !ok {
+		return "", false
+	}
+	return typeSpec.Name.Name, true
+}
+
+// queryVarsAssertionType returns the name of T for 'var _ QueryVars = &T{}'.
+func queryVarsAssertionType(decl ast.Decl) (string, bool) {
+	genDecl, ok := decl.(*ast.GenDecl)
+	if !ok || genDecl.Tok != token.VAR || len(genDecl.Specs) != 1 {
+		return "", false
+	}
+	valueSpec := genDecl.Specs[0].(*ast.ValueSpec)
+	if len(valueSpec.Names) != 1 || valueSpec.Names[0].Name != "_" || len(valueSpec.Values) != 1 {
+		return "", false
+	}
+	unary, ok := valueSpec.Values[0].(*ast.UnaryExpr)
+	if !ok || unary.Op != token.AND {
+		return "", false
+	}
+	lit, ok := unary.X.(*ast.CompositeLit)
+	if !ok || len(lit.Elts) != 0 {
+		return "", false
+	}
+	typeName, ok := lit.Type.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	return typeName.Name, true
+}
+
+// formatConstStructName returns the name of T for a declaration of
+// the TFormat and TArgCount constants.
+func formatConstStructName(decl *ast.GenDecl) (string, bool) {
+	var typeName string
+	for _, spec := range decl.Specs {
+		for _, name := range spec.(*ast.ValueSpec).Names {
+			nameTypeName, ok := strings.CutSuffix(name.Name, "Format")
+			if !ok {
+				nameTypeName, ok = strings.CutSuffix(name.Name, "ArgCount")
+			}
+			if !ok || (typeName != "" && nameTypeName != typeName) {
+				return "", false
+			}
+			typeName = nameTypeName
+		}
+	}
+	return typeName, typeName != ""
+}
+
+// isFormatConstDecl checks for the TFormat and TArgCount constants.
+func isFormatConstDecl(decl *ast.GenDecl, structNames Set[string]) bool {
+	for _, spec := range decl.Specs {
+		for _, name := range spec.(*ast.ValueSpec).Names {
+			typeName, ok := strings.CutSuffix(name.Name, "Format")
+			if !ok {
+				typeName, ok = strings.CutSuffix(name.Name, "ArgCount")
+			}
+			if !ok || !structNames.Has(typeName) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const generatedFile = `// Code generated by querygen.
+package p
+
+import (
+	"github.com/sourcegraph/querygen/lib/interpolate"
+	_ "math"
+)
+
+type fooQueryVars struct {
+	x int
+}
+
+var _ interpolate.QueryVars = &fooQueryVars{}
+
+const (
+	fooQueryVarsFormat   = ` + "`SELECT %s`" + `
+	fooQueryVarsArgCount = 1
+)
+
+func (qp *fooQueryVars) FormatArgs() []any {
+	return []any{qp.x}
+}
+
+func (qp *fooQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(fooQueryVarsFormat, fooQueryVarsArgCount, qp)
+}
+
+func (qp *fooQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(fooQueryVarsFormat, fooQueryVarsArgCount, qp)
+}
+`
+
+func TestFindHandWrittenDecl(t *testing.T) {
+	testCases := []struct {
+		name     string
+		extra    string
+		wantDecl bool
+	}{
+		{name: "generated only"},
+		{name: "function", extra: "func helper() {}", wantDecl: true},
+		{name: "struct", extra: "type other struct{}", wantDecl: true},
+		{name: "extra method", extra: "func (qp *fooQueryVars) String() string { return \"\" }", wantDecl: true},
+		{name: "const", extra: "const limit = 10", wantDecl: true},
+		{name: "var", extra: "var cache = map[string]int{}", wantDecl: true},
+		{name: "type alias", extra: "type bar = fooQueryVars", wantDecl: true},
+		{name: "interface", extra: "type iface interface{}", wantDecl: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), "p_query_gen.go", generatedFile+testCase.extra, parser.ParseComments)
+			require.NoError(t, err)
+			decl := FindHandWrittenDecl(file)
+			if !testCase.wantDecl {
+				require.Nil(t, decl)
+				return
+			}
+			require.NotNil(t, decl)
+			require.Same(t, file.Decls[len(file.Decls)-1], decl)
+		})
+	}
+}
+
+func TestGeneratedDeclStructName(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "p_query_gen.go", generatedFile+"const limit = 10", parser.ParseComments)
+	require.NoError(t, err)
+	var names []string
+	for _, decl := range file.Decls[1:] {
+		name, ok := GeneratedDeclStructName(decl)
+		if !ok {
+			name = "-"
+		}
+		names = append(names, name)
+	}
+	require.Equal(t, []string{"fooQueryVars", "fooQueryVars", "fooQueryVars", "fooQueryVars", "fooQueryVars", "fooQueryVars", "-"}, names)
+}
@@ -4,16 +4,34 @@ import (
 	"bytes"
 	"fmt"
 	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"golang.org/x/tools/go/analysis"
 
-	"github.com/grafana/regexp"
 	"github.com/wk8/go-ordered-map/v2"
 
 	"github.com/sourcegraph/sourcegraph/lib/errors"
 )
 
 type StructFactory struct {
-	Pass *analysis.Pass
+	Pass      *analysis.Pass
+	Settings  *Settings
+	Fragments FragmentResolver
+}
+
+// FragmentResolver finds the types generated for query constants
+// used in fragment(ref) slots.
+type FragmentResolver interface {
+	// FragmentVarsType returns the name of the Vars type generated for
+	// the query constant ref, as seen from the file containing pos,
+	// along with the imports needed for it.
+	FragmentVarsType(pos token.Pos, ref string) (string, []ImportSpec, error)
 }
 
 // NewGoStruct attempts to build a GoStruct from the given query string.
@@ -22,26 +40,103 @@ type StructFactory struct {
 // 1. If the query was using string interpolation and is well-formed: returns a GoStruct
 // 2. If the query was using string interpolation and is ill-formed: returns nil, err
 // 3. If the query was not using string interpolation: returns nil, nil
-func (factory *StructFactory) NewGoStruct(subregex *regexp.Regexp, queryConst *ast.Ident, templateText string) (*GoStruct, error) {
-	structBuilder := newStructBuilder(factory.Pass, queryConst)
-	for i, matches := range subregex.FindAllStringSubmatch(templateText, -1) {
-		if err := structBuilder.AddInterpolationMatch(i, matches); err != nil {
-			factory.Pass.Reportf(queryConst.Pos(), "ill-formed interpolation: %v", err)
+//
+// Diagnostics are reported at the offending interpolation inside
+// the string literals the query was folded from.
+func (factory *StructFactory) NewGoStruct(queryConst *ast.Ident, typeName string, query FoldedString) (*GoStruct, error) {
+	template, templateErrs := ParseTemplate(query.Text)
+	if len(templateErrs) != 0 {
+		for _, err := range templateErrs {
+			factory.Pass.Report(analysis.Diagnostic{
+				Pos:     query.Pos(err.Start),
+				End:     query.Pos(err.End),
+				Message: fmt.Sprintf("ill-formed interpolation in %s: %s", queryConst.Name, err.Message),
+			})
+		}
+		return nil, templateErrs[0]
+	}
+	structBuilder := newStructBuilder(factory.Pass, factory.Settings, factory.Fragments, queryConst, typeName, query)
+	index := 0
+	for _, node := range allNodes(template.Nodes) {
+		var err error
+		switch node := node.(type) {
+		case *InterpolationNode:
+			err = structBuilder.AddInterpolation(index, node)
+			index++
+		case *SectionNode:
+			err = structBuilder.AddSection(node)
+		}
+		if err != nil {
 			return nil, err
 		}
 	}
-	return structBuilder.tryBuild(), nil
+	format, sites := template.SqlfFormat()
+	return structBuilder.tryBuild(format, sites), nil
 }
 
 type GoStruct struct {
 	TypeName string
 	Fields   []GoStructField
+	// QueryConst is the identifier of the query constant
+	// this struct was generated from.
+	QueryConst *ast.Ident
+	// Format is the format string for sqlf.Sprintf equivalent
+	// to the query text.
+	Format string
+	// Sites are the interpolation sites for Format.
+	Sites []InterpolationSite
+	// SourceFile is the base name of the file declaring QueryConst.
+	SourceFile string
+	// Text is the folded query text.
+	Text string
 }
 
 type GoStructField struct {
-	Name    string
-	Type    TypeName
-	Indexes []int
+	// Name is the name of the interpolation variable.
+	// See GoName for the name of the struct field.
+	Name string
+	Type TypeName
+	Uses []FieldUse
+	// ResolvedType is the type-checked form of Type.
+	// nil if the type could not be resolved.
+	ResolvedType types.Type
+	// Imports are the imports needed for the package qualifiers in Type.
+	Imports []ImportSpec
+}
+
+// GoName returns the name of the struct field for f.
+//
+// Exported names drop any leading underscores before upper-casing the
+// first letter, so "_id" becomes "Id". Names which still aren't exported,
+// such as "_", are reported by checkGoName.
+func (f *GoStructField) GoName(exported bool) string {
+	if !exported {
+		return f.Name
+	}
+	name := strings.TrimLeft(f.Name, "_")
+	if name == "" {
+		return f.Name
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+// FieldUse represents a single interpolation of a field in the query text.
+type FieldUse struct {
+	// Index is the 0-based index of the interpolation in the query text.
+	Index int
+	// FormatSpec is the format specifier used for this interpolation,
+	// or for each element in ModeList.
+	FormatSpec string
+	Mode       InterpolationMode
+	// Separator and Empty are the list options, as in InterpolationNode.
+	Separator string
+	Empty     *string
+	// Section is set for uses as the condition of a conditional section,
+	// which aren't format arguments themselves.
+	Section bool
+	// Span is the range of the interpolation in the folded query text.
+	Span Span
 }
 
 type TypeName struct {
@@ -50,19 +145,37 @@ type TypeName struct {
 
 type goStructBuilder struct {
 	pass       *analysis.Pass
+	settings   *Settings
+	fragments  FragmentResolver
 	queryConst *ast.Ident
+	typeName   string
+	query      FoldedString
 	fieldMap   *orderedmap.OrderedMap[string, *GoStructField]
 }
 
-func newStructBuilder(pass *analysis.Pass, queryConst *ast.Ident) goStructBuilder {
+func newStructBuilder(pass *analysis.Pass, settings *Settings, fragments FragmentResolver, queryConst *ast.Ident, typeName string, query FoldedString) goStructBuilder {
 	return goStructBuilder{
 		pass,
+		settings,
+		fragments,
 		queryConst,
+		typeName,
+		query,
 		orderedmap.New[string, *GoStructField](),
 	}
 }
 
-func (b *goStructBuilder) tryBuild() *GoStruct {
+// reportAt reports a diagnostic for the given span of the query text.
+func (b *goStructBuilder) reportAt(span Span, related []analysis.RelatedInformation, format string, args ...any) {
+	b.pass.Report(analysis.Diagnostic{
+		Pos:     b.query.Pos(span.Start),
+		End:     b.query.Pos(span.End),
+		Message: fmt.Sprintf(format, args...),
+		Related: related,
+	})
+}
+
+func (b *goStructBuilder) tryBuild(format string, sites []InterpolationSite) *GoStruct {
 	if b.fieldMap.Len() == 0 {
 		return nil
 	}
@@ -70,72 +183,316 @@ func (b *goStructBuilder) tryBuild() *GoStruct {
 	for it := b.fieldMap.Oldest(); it != nil; it = it.Next() {
 		fields = append(fields, *it.Value)
 	}
-	return &GoStruct{b.queryConst.Name + "Vars", fields}
+	sourceFile := filepath.Base(b.pass.Fset.Position(b.queryConst.Pos()).Filename)
+	return &GoStruct{b.typeName, fields, b.queryConst, format, sites, sourceFile, b.query.Text}
 }
 
-func (b *goStructBuilder) AddInterpolationMatch(index int, matches []string) error {
-	fieldBuilder := NewFieldBuilder(index, matches)
+func (b *goStructBuilder) AddInterpolation(index int, node *InterpolationNode) error {
+	return b.addField(NewFieldBuilder(index, node))
+}
+
+// AddSection adds the bool field controlling a conditional section.
+func (b *goStructBuilder) AddSection(node *SectionNode) error {
+	return b.addField(NewSectionFieldBuilder(node))
+}
+
+func (b *goStructBuilder) addField(fieldBuilder GoStructFieldBuilder) error {
+	// Interpolations from queries in other packages use the type
+	// recorded for them, since it may not be in scope here.
+	var presetField *GoStructField
+	if fact, factField := b.factFieldFor(fieldBuilder); factField != nil {
+		typeName, imports, resolvedType, err := fieldFromFact(b.pass, b.queryConst.Pos(), fact, factField)
+		if err != nil {
+			b.reportAt(fieldBuilder.Span, nil, "%v", err)
+			return err
+		}
+		fieldBuilder.TypeName = typeName.Name
+		presetField = &GoStructField{fieldBuilder.Name, typeName, []FieldUse{fieldBuilder.use()}, resolvedType, imports}
+	} else if fieldBuilder.Fragment {
+		typeName, imports, err := b.fragmentType(fieldBuilder)
+		if err != nil {
+			b.reportAt(fieldBuilder.Span, nil, "invalid fragment %v in %s: %v", fieldBuilder.Name, b.queryConst.Name, err)
+			return err
+		}
+		fieldBuilder.TypeName = typeName
+		// The type isn't resolved, since the Vars type may not have
+		// been generated yet.
+		presetField = &GoStructField{fieldBuilder.Name, TypeName{typeName}, []FieldUse{fieldBuilder.use()}, nil, imports}
+	}
 
 	if fieldData, found := b.fieldMap.Get(fieldBuilder.Name); found {
-		return b.emitExtraTypeHint(fieldBuilder, b.mergeFieldData(fieldData, fieldBuilder))
+		if err := b.mergeFieldData(fieldData, fieldBuilder); err != nil {
+			return b.emitExtraTypeHint(fieldBuilder, err)
+		}
+		return b.checkMode(fieldData, fieldBuilder)
 	}
 
-	newFieldData, err := b.createNewField(fieldBuilder)
-	if err != nil {
-		return b.emitExtraTypeHint(fieldBuilder, err)
+	newFieldData := presetField
+	if newFieldData == nil {
+		var err error
+		newFieldData, err = b.createNewField(fieldBuilder)
+		if err != nil {
+			return b.emitExtraTypeHint(fieldBuilder, err)
+		}
+	}
+	if err := b.checkGoName(newFieldData, fieldBuilder); err != nil {
+		return err
+	}
+	if err := b.checkMode(newFieldData, fieldBuilder); err != nil {
+		return err
 	}
 
 	b.fieldMap.Set(newFieldData.Name, newFieldData)
 	return nil
 }
 
+// fragmentType returns the type of the field for a fragment slot: any query
+// for {{x : fragment}}, or a Fragment of the referenced query's Vars type
+// for {{x : fragment(ref)}}.
+func (b *goStructBuilder) fragmentType(fieldBuilder GoStructFieldBuilder) (string, []ImportSpec, error) {
+	var imports []ImportSpec
+	runtimePrefix := ""
+	if qualifier := b.settings.RuntimeQualifier(b.pass.Pkg); qualifier != "" {
+		runtimePrefix = qualifier + "."
+		imports = append(imports, ImportSpec{Name: qualifier, Path: b.settings.RuntimePackage, PkgName: qualifier})
+	}
+	if fieldBuilder.FragmentRef == "" {
+		return "*" + runtimePrefix + "Query", imports, nil
+	}
+	varsType, varsImports, err := b.fragments.FragmentVarsType(b.queryConst.Pos(), fieldBuilder.FragmentRef)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%sFragment[*%s]", runtimePrefix, varsType), append(imports, varsImports...), nil
+}
+
+// checkGoName reports an error if the struct field for newField
+// would have the same name as that of an existing field, which happens
+// with exported fields for names like "id" and "Id", or if it should
+// be exported but can't be, as for "_".
+func (b *goStructBuilder) checkGoName(newField *GoStructField, fieldBuilder GoStructFieldBuilder) error {
+	goName := newField.GoName(b.settings.ExportedFields)
+	if b.settings.ExportedFields && !token.IsExported(goName) {
+		err := errors.Newf("struct field for %v cannot be exported, since it doesn't start with an upper-case letter", newField.Name)
+		b.reportAt(fieldBuilder.Span, nil, "ill-formed interpolation in %s: %v", b.queryConst.Name, err)
+		return err
+	}
+	for it := b.fieldMap.Oldest(); it != nil; it = it.Next() {
+		field := it.Value
+		if field.GoName(b.settings.ExportedFields) != goName {
+			continue
+		}
+		err := errors.Newf("fields %v and %v both map to struct field %v", field.Name, newField.Name, goName)
+		firstUse := field.Uses[0].Span
+		b.reportAt(fieldBuilder.Span, []analysis.RelatedInformation{{
+			Pos:     b.query.Pos(firstUse.Start),
+			End:     b.query.Pos(firstUse.End),
+			Message: fmt.Sprintf("%v first used here", field.Name),
+		}}, "ill-formed interpolation in %s: %v", b.queryConst.Name, err)
+		return err
+	}
+	return nil
+}
+
+// checkMode reports an error if the type of field doesn't support
+// the mode of the interpolation.
+func (b *goStructBuilder) checkMode(field *GoStructField, fieldBuilder GoStructFieldBuilder) error {
+	if fieldBuilder.Mode == ModeValue || field.ResolvedType == nil {
+		return nil
+	}
+	var err error
+	slice, isSlice := field.ResolvedType.Underlying().(*types.Slice)
+	switch {
+	case !isSlice:
+		err = errors.Newf("%v mode requires a slice type, but %v has type %v",
+			fieldBuilder.Mode, field.Name, field.Type.Name)
+	case fieldBuilder.Mode == ModeArray && !isArrayElemType(slice.Elem()):
+		err = errors.Newf("%v mode requires a slice of booleans, integers, floats, strings or []byte "+
+			"(or pointers to them), but %v has element type %v",
+			fieldBuilder.Mode, field.Name, types.TypeString(slice.Elem(), types.RelativeTo(b.pass.Pkg)))
+	default:
+		return nil
+	}
+	b.reportAt(fieldBuilder.Span, nil, "ill-formed interpolation in %s: %v", b.queryConst.Name, err)
+	return err
+}
+
+// isArrayElemType reports whether elements of type t can be encoded
+// in a Postgres array literal by interpolate.ArrayArg.
+func isArrayElemType(t types.Type) bool {
+	if pointer, ok := t.Underlying().(*types.Pointer); ok {
+		t = pointer.Elem()
+	}
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		return t.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) != 0 &&
+			t.Info()&types.IsUntyped == 0 && t.Kind() != types.Uintptr
+	case *types.Slice:
+		elem, ok := t.Elem().Underlying().(*types.Basic)
+		return ok && elem.Kind() == types.Byte
+	default:
+		return false
+	}
+}
+
+// factFieldFor returns the field of the query from another package
+// which the interpolation comes from, if any.
+func (b *goStructBuilder) factFieldFor(fieldBuilder GoStructFieldBuilder) (*QueryFact, *QueryFactField) {
+	fact, factStart := b.query.FactAt(fieldBuilder.Span.Start)
+	if fact == nil {
+		return nil, nil
+	}
+	for i := range fact.Fields {
+		factField := &fact.Fields[i]
+		if factField.Name != fieldBuilder.Name {
+			continue
+		}
+		for _, span := range factField.Spans {
+			if span.Start == fieldBuilder.Span.Start-factStart {
+				return fact, factField
+			}
+		}
+	}
+	return nil, nil
+}
+
 func (b *goStructBuilder) createNewField(fieldBuilder GoStructFieldBuilder) (*GoStructField, error) {
 	if fieldBuilder.TypeName == "_" {
-		return nil, errors.Newf("first interpolation of %v must specify type", fieldBuilder.Name)
+		err := errors.Newf("first interpolation of %v must specify type", fieldBuilder.Name)
+		b.reportAt(fieldBuilder.Span, nil, "ill-formed interpolation in %s: %v", b.queryConst.Name, err)
+		return nil, err
+	}
+	resolvedType, err := ResolveTypeName(b.pass, b.queryConst.Pos(), fieldBuilder.TypeName)
+	if err != nil {
+		b.reportAt(fieldBuilder.Span, nil, "invalid type %v for interpolation variable %v: %v",
+			fieldBuilder.TypeName, fieldBuilder.Name, err)
+		// Only generate the field if configured to, using the type as written.
+		if b.settings.RejectInvalidTypes {
+			return nil, err
+		}
 	}
 	return &GoStructField{
 		fieldBuilder.Name,
 		TypeName{fieldBuilder.TypeName},
-		[]int{fieldBuilder.Index},
+		[]FieldUse{fieldBuilder.use()},
+		resolvedType,
+		TypeNameImports(b.pass, b.queryConst.Pos(), fieldBuilder.TypeName),
 	}, nil
 }
 
 func (b *goStructBuilder) mergeFieldData(field *GoStructField, fieldBuilder GoStructFieldBuilder) error {
 	if fieldBuilder.TypeName != "_" && fieldBuilder.TypeName != field.Type.Name {
-		return errors.Newf("field %v used with distinct types: %v and %v",
+		err := errors.Newf("field %v used with distinct types: %v and %v",
 			field.Name, field.Type.Name, fieldBuilder.TypeName)
+		firstUse := field.Uses[0].Span
+		b.reportAt(fieldBuilder.Span, []analysis.RelatedInformation{{
+			Pos:     b.query.Pos(firstUse.Start),
+			End:     b.query.Pos(firstUse.End),
+			Message: fmt.Sprintf("%v first used with type %v here", field.Name, field.Type.Name),
+		}}, "ill-formed interpolation in %s: %v", b.queryConst.Name, err)
+		return err
 	}
-	field.Indexes = append(field.Indexes, fieldBuilder.Index)
+	field.Uses = append(field.Uses, fieldBuilder.use())
 	return nil
 }
 
-func WriteStructs(wanted []GoStruct, buf *bytes.Buffer, shouldImportInterpolate bool) {
-	packagePrefix := "interpolate."
-	if !shouldImportInterpolate {
-		packagePrefix = ""
+func (fieldBuilder GoStructFieldBuilder) use() FieldUse {
+	formatSpec := fieldBuilder.FormatSpec
+	if formatSpec == "" {
+		formatSpec = DefaultFormatSpec
+	}
+	return FieldUse{
+		Index:      fieldBuilder.Index,
+		FormatSpec: formatSpec,
+		Mode:       fieldBuilder.Mode,
+		Separator:  fieldBuilder.Separator,
+		Empty:      fieldBuilder.Empty,
+		Section:    fieldBuilder.Section,
+		Span:       fieldBuilder.Span,
+	}
+}
+
+// WriteStructs writes the declarations for wanted to buf.
+//
+// runtimePackageName is the qualifier for the runtime package,
+// or empty if the structs are generated in that package.
+func WriteStructs(wanted []GoStruct, buf *bytes.Buffer, runtimePackageName string, exportedFields bool) {
+	packagePrefix := ""
+	if runtimePackageName != "" {
+		packagePrefix = runtimePackageName + "."
+	}
+	// When queries from multiple source files share a generated file,
+	// mark where each group of structs comes from.
+	multipleSources := false
+	for _, goStruct := range wanted {
+		if goStruct.SourceFile != wanted[0].SourceFile {
+			multipleSources = true
+		}
 	}
 	for j, goStruct := range wanted {
+		if multipleSources && (j == 0 || wanted[j-1].SourceFile != goStruct.SourceFile) {
+			buf.WriteString(fmt.Sprintf("// Generated from queries in %s.\n\n", goStruct.SourceFile))
+		}
 		buf.WriteString(fmt.Sprintf("type %s struct {\n", goStruct.TypeName))
 		for _, field := range goStruct.Fields {
-			buf.WriteString(fmt.Sprintf("\t%s %s\n", field.Name, field.Type.Name))
+			buf.WriteString(fmt.Sprintf("\t%s %s\n", field.GoName(exportedFields), field.Type.Name))
 		}
 		buf.WriteString("}\n\n")
 
 		buf.WriteString(fmt.Sprintf("var _ %sQueryVars = &%s{}\n\n", packagePrefix, goStruct.TypeName))
 
-		fieldNameForIndex := map[int]string{}
+		argForOffset := map[int]string{}
 		for _, field := range goStruct.Fields {
-			for _, index := range field.Indexes {
-				fieldNameForIndex[index] = field.Name
+			for _, use := range field.Uses {
+				if use.Section {
+					continue
+				}
+				arg := "qp." + field.GoName(exportedFields)
+				switch use.Mode {
+				case ModeList:
+					arg = fmt.Sprintf("%sList(%s, %q)", packagePrefix, arg, use.FormatSpec)
+					if use.Separator != DefaultListSeparator {
+						arg += fmt.Sprintf(".WithSeparator(%q)", use.Separator)
+					}
+					if use.Empty != nil {
+						arg += fmt.Sprintf(".OrEmpty(%q)", *use.Empty)
+					}
+				case ModeArray:
+					arg = fmt.Sprintf("%sArray(%s)", packagePrefix, arg)
+				}
+				argForOffset[use.Span.Start] = arg
 			}
 		}
+		args := formatArgs(goStruct.Sites, argForOffset, packagePrefix, exportedFields)
+
+		buf.WriteString("const (\n")
+		buf.WriteString(fmt.Sprintf("\t// %sFormat is the sqlf format string for %s.\n",
+			goStruct.TypeName, goStruct.QueryConst.Name))
+		buf.WriteString(fmt.Sprintf("\t%sFormat = %s\n", goStruct.TypeName, quoteGoString(goStruct.Format)))
+		buf.WriteString(fmt.Sprintf("\t// %sArgCount is the number of arguments for %sFormat.\n",
+			goStruct.TypeName, goStruct.TypeName))
+		buf.WriteString(fmt.Sprintf("\t%sArgCount = %d\n", goStruct.TypeName, len(args)))
+		buf.WriteString(")\n\n")
 
 		buf.WriteString(fmt.Sprintf("func (qp *%s) FormatArgs() []any {\n", goStruct.TypeName))
 		buf.WriteString("\treturn []any{")
-		for i := 0; i < len(fieldNameForIndex); i++ {
-			buf.WriteString(fmt.Sprintf("qp.%s,", fieldNameForIndex[i]))
+		for _, arg := range args {
+			buf.WriteString(arg + ",")
 		}
 		buf.WriteString("}\n")
+		buf.WriteString("}\n\n")
+
+		buf.WriteString(fmt.Sprintf("// Build creates a *sqlf.Query from %s using these vars.\n", goStruct.QueryConst.Name))
+		buf.WriteString("// It returns an error for an empty list or an unset fragment.\n")
+		buf.WriteString(fmt.Sprintf("func (qp *%s) Build() (*%sQuery, error) {\n", goStruct.TypeName, packagePrefix))
+		buf.WriteString(fmt.Sprintf("\treturn %sDoFormat(%sFormat, %sArgCount, qp)\n",
+			packagePrefix, goStruct.TypeName, goStruct.TypeName))
+		buf.WriteString("}\n\n")
+
+		buf.WriteString("// MustBuild is like Build but panics if Build returns an error.\n")
+		buf.WriteString(fmt.Sprintf("func (qp *%s) MustBuild() *%sQuery {\n", goStruct.TypeName, packagePrefix))
+		buf.WriteString(fmt.Sprintf("\treturn %sMustDoFormat(%sFormat, %sArgCount, qp)\n",
+			packagePrefix, goStruct.TypeName, goStruct.TypeName))
 		if j == len(wanted)-1 {
 			buf.WriteString("}\n")
 		} else {
@@ -145,6 +502,37 @@ func WriteStructs(wanted []GoStruct, buf *bytes.Buffer, shouldImportInterpolate
 	}
 }
 
+// formatArgs returns the expressions for the arguments for sites, given
+// those for the interpolations by offset. The arguments for conditional
+// sections are Section calls with the arguments for their contents.
+func formatArgs(sites []InterpolationSite, argForOffset map[int]string, packagePrefix string, exportedFields bool) []string {
+	args := make([]string, len(sites))
+	for i, site := range sites {
+		section := site.Section
+		if section == nil {
+			args[i] = argForOffset[site.Offset]
+			continue
+		}
+		conditionField := GoStructField{Name: section.Name}
+		sectionArgs := []string{"qp." + conditionField.GoName(exportedFields), quoteGoString(section.Format)}
+		if section.Negated {
+			sectionArgs[0] = "!" + sectionArgs[0]
+		}
+		sectionArgs = append(sectionArgs, formatArgs(section.Sites, argForOffset, packagePrefix, exportedFields)...)
+		args[i] = fmt.Sprintf("%sSection(%s)", packagePrefix, strings.Join(sectionArgs, ", "))
+	}
+	return args
+}
+
+// quoteGoString returns a Go string literal for s, preferring
+// a raw string literal to keep multi-line queries readable.
+func quoteGoString(s string) string {
+	if strings.ContainsAny(s, "`\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
 type cannotAutomaticallyFormatError struct {
 	typeName string
 }
@@ -157,12 +545,12 @@ func (c *cannotAutomaticallyFormatError) Error() string {
 
 func (b *goStructBuilder) emitExtraTypeHint(fieldBuilder GoStructFieldBuilder, err error) error {
 	if errors.HasType[*cannotAutomaticallyFormatError](err) {
-		b.pass.Reportf(b.queryConst.Pos(),
+		b.reportAt(fieldBuilder.Span, nil,
 			"cannot handle type %v of interpolation variable %v;"+
 				" it should be a basic type (int, uint, string) "+
 				"or have a basic type as its underlying type",
 			fieldBuilder.TypeName, fieldBuilder.Name)
-		b.pass.Reportf(b.queryConst.Pos(),
+		b.reportAt(fieldBuilder.Span, nil,
 			"HINT: you can specify a custom format specifier using {{fieldName : type : %%d}} syntax")
 		return err
 	}
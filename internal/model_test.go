@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoName(t *testing.T) {
+	testCases := []struct {
+		name       string
+		unexported string
+		exported   string
+	}{
+		{name: "repoID", unexported: "repoID", exported: "RepoID"},
+		{name: "RepoID", unexported: "RepoID", exported: "RepoID"},
+		{name: "_offset", unexported: "_offset", exported: "Offset"},
+		{name: "__x_y", unexported: "__x_y", exported: "X_y"},
+		{name: "élan", unexported: "élan", exported: "Élan"},
+		// Not exported, so reported by checkGoName.
+		{name: "_", unexported: "_", exported: "_"},
+		{name: "_1", unexported: "_1", exported: "1"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			field := GoStructField{Name: testCase.name}
+			require.Equal(t, testCase.unexported, field.GoName(false))
+			require.Equal(t, testCase.exported, field.GoName(true))
+		})
+	}
+}
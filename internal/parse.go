@@ -1,42 +1,168 @@
 package internal
 
 import (
-	"fmt"
+	"strings"
 
 	"github.com/grafana/regexp"
 )
 
-// SubstitutionRegex represents interpolation syntax.
-// Conceptually, the following syntaxes are allowed
-//
-//	{{ fieldName : typeName }}
-//	{{ fieldName : _ }} // allowed for 2nd, 3rd etc. interpolation of same field
-//
-// formatSpec must not contain positional arguments (i.e. %[0]f is not OK)
-var SubstitutionRegex *regexp.Regexp = func() *regexp.Regexp {
-	rawIdentifier := `[a-zA-Z_][a-zA-Z0-9_]*`
-	// Q: Should we simplify this to parse everything?
-	typeName := fmt.Sprintf(`(\*?%s\.)?[a-zA-Z0-9_*\[\] ]+`, rawIdentifier)
-	return regexp.MustCompile(fmt.Sprintf(
-		`{{\s*(%s)\s*:\s*(%s)\s*(:\s*(%%(.+?))\s*)?}}`, rawIdentifier, typeName))
-}()
+// DefaultFormatSpec is used for interpolations which don't specify
+// a format specifier explicitly.
+const DefaultFormatSpec = "%s"
+
+var formatSpecRegex = regexp.MustCompile(`^%[-+# 0]*[0-9]*(\.[0-9]*)?[a-zA-Z]$`)
 
 // GoStructFieldBuilder maps N-1 to GoStructField, as the same field
 // may be interpolated multiple times in the same query.
 type GoStructFieldBuilder struct {
 	Name     string
 	TypeName string
-	Index    int
+	// Fragment and FragmentRef are set for fragment slots,
+	// as in InterpolationNode.
+	Fragment    bool
+	FragmentRef string
+	// FormatSpec is the optional format specifier, e.g. %d.
+	// Empty if not specified.
+	FormatSpec string
+	Mode       InterpolationMode
+	// Separator and Empty are the list options, as in InterpolationNode.
+	Separator string
+	Empty     *string
+	// Section is set for the condition of a conditional section.
+	Section bool
+	Index   int
+	// Span is the range of the interpolation in the query text.
+	Span Span
 }
 
-func NewFieldBuilder(matchIndex int, matches []string) GoStructFieldBuilder {
-	if len(matches) < 3 {
-		panic("expected field name at index 1, type name at index 2")
+// NewSectionFieldBuilder creates the builder for the bool field
+// controlling a conditional section.
+func NewSectionFieldBuilder(node *SectionNode) GoStructFieldBuilder {
+	return GoStructFieldBuilder{
+		Name:     node.Name,
+		TypeName: "bool",
+		Section:  true,
+		Index:    -1,
+		Span:     node.OpenSpan,
 	}
+}
+
+func NewFieldBuilder(index int, node *InterpolationNode) GoStructFieldBuilder {
 	return GoStructFieldBuilder{
-		Name:     matches[1],
-		TypeName: matches[2],
-		Index:    matchIndex,
+		Name:        node.Name,
+		TypeName:    node.TypeName,
+		Fragment:    node.Fragment,
+		FragmentRef: node.FragmentRef,
+		FormatSpec:  node.FormatSpec,
+		Mode:        node.Mode,
+		Separator:   node.Separator,
+		Empty:       node.Empty,
+		Index:       index,
+		Span:        node.Span,
+	}
+}
+
+// InterpolationSite describes a single interpolation or conditional
+// section in a query's text.
+type InterpolationSite struct {
+	// Offset is the byte offset of the interpolation in the query text.
+	Offset int
+	// FormatSpec is the format specifier used for the interpolation,
+	// or for each element in ModeList.
+	FormatSpec string
+	// TypeName is the type of the interpolation, as in InterpolationNode.
+	TypeName string
+	// Fragment is set for fragment slots, as in InterpolationNode.
+	Fragment bool
+	Mode     InterpolationMode
+	// Separator and Empty are the list options, as in InterpolationNode.
+	Separator string
+	Empty     *string
+	// Section is set for conditional sections, which are formatted
+	// using %s and replaced by their contents or nothing.
+	Section *SectionSite
+}
+
+// SectionSite describes the contents of a conditional section.
+type SectionSite struct {
+	// Name is the bool field controlling the section.
+	Name    string
+	Negated bool
+	// Format and Sites are for the contents of the section,
+	// as returned by SqlfFormat.
+	Format string
+	Sites  []InterpolationSite
+}
+
+// SqlfFormat returns the format string for sqlf.Sprintf corresponding
+// to the template, by replacing every interpolation with its format
+// specifier (or DefaultFormatSpec), along with the interpolation sites
+// in the order they occur.
+//
+// Interpolations using ModeList always use %s, since their elements
+// are joined into a nested *sqlf.Query. Conditional sections also use
+// %s, and their contents are described by a nested format string and
+// sites, so that the format string doesn't depend on the conditions.
+//
+// Literal % characters outside interpolations (e.g. in LIKE patterns or
+// modulo expressions) are escaped as %% so that sqlf doesn't treat them
+// as format verbs.
+func (t *Template) SqlfFormat() (string, []InterpolationSite) {
+	return sqlfFormat(t.Nodes, len(t.Text))
+}
+
+func sqlfFormat(nodes []TemplateNode, sizeHint int) (string, []InterpolationSite) {
+	var buf strings.Builder
+	buf.Grow(sizeHint)
+	var sites []InterpolationSite
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *TextNode:
+			writeEscapedPercents(&buf, node.Text)
+		case *EscapeNode:
+			buf.WriteString("{{")
+		case *InterpolationNode:
+			formatSpec := node.FormatSpec
+			if formatSpec == "" {
+				formatSpec = DefaultFormatSpec
+			}
+			if node.Mode == ModeList {
+				buf.WriteString(DefaultFormatSpec)
+			} else {
+				buf.WriteString(formatSpec)
+			}
+			sites = append(sites, InterpolationSite{
+				Offset:     node.Start,
+				FormatSpec: formatSpec,
+				TypeName:   node.TypeName,
+				Fragment:   node.Fragment,
+				Mode:       node.Mode,
+				Separator:  node.Separator,
+				Empty:      node.Empty,
+			})
+		case *SectionNode:
+			buf.WriteString(DefaultFormatSpec)
+			format, sectionSites := sqlfFormat(node.Nodes, node.End-node.OpenSpan.End)
+			sites = append(sites, InterpolationSite{
+				Offset:     node.Start,
+				FormatSpec: DefaultFormatSpec,
+				Section:    &SectionSite{node.Name, node.Negated, format, sectionSites},
+			})
+		}
+	}
+	return buf.String(), sites
+}
+
+func writeEscapedPercents(buf *strings.Builder, text string) {
+	for {
+		i := strings.IndexByte(text, '%')
+		if i < 0 {
+			buf.WriteString(text)
+			return
+		}
+		buf.WriteString(text[:i+1])
+		buf.WriteByte('%')
+		text = text[i+1:]
 	}
 }
 
@@ -1,14 +1,14 @@
 package internal
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/hexops/autogold/v2"
 	"github.com/stretchr/testify/require"
 )
 
-func TestSubstitutionRegex(t *testing.T) {
-	re := SubstitutionRegex
+func TestNewFieldBuilder(t *testing.T) {
 	type testCase struct {
 		input    string
 		builders autogold.Value
@@ -17,46 +17,117 @@ func TestSubstitutionRegex(t *testing.T) {
 		{input: "{{foo: bar}}", builders: autogold.Expect([]GoStructFieldBuilder{{
 			Name:     "foo",
 			TypeName: "bar",
+			Span:     Span{End: 12},
 		}})},
 		{input: "{{foo: string}}", builders: autogold.Expect([]GoStructFieldBuilder{{
 			Name:     "foo",
 			TypeName: "string",
+			Span:     Span{End: 15},
 		}})},
 		{input: "{{ foo: abc.X}}", builders: autogold.Expect([]GoStructFieldBuilder{{
 			Name:     "foo",
 			TypeName: "abc.X",
+			Span:     Span{End: 15},
+		}})},
+		{input: "{{ limit : int : %d }}", builders: autogold.Expect([]GoStructFieldBuilder{{
+			Name:       "limit",
+			TypeName:   "int",
+			FormatSpec: "%d",
+			Span:       Span{End: 22},
 		}})},
 		{input: "SELECT {{col: string}} from {{foo: string}}", builders: autogold.Expect([]GoStructFieldBuilder{
 			{
 				Name:     "col",
 				TypeName: "string",
+				Span: Span{
+					Start: 7,
+					End:   22,
+				},
 			},
 			{
 				Name:     "foo",
 				TypeName: "string",
 				Index:    1,
+				Span: Span{
+					Start: 28,
+					End:   43,
+				},
 			},
 		})},
 		{
 			input: `SELECT * from T WHERE X = {{x: *int: %s}} AND Y = {{uploadedParts: any}}`, builders: autogold.Expect([]GoStructFieldBuilder{
 				{
-					Name:     "x",
-					TypeName: "*int",
+					Name:       "x",
+					TypeName:   "*int",
+					FormatSpec: "%s",
+					Span: Span{
+						Start: 26,
+						End:   41,
+					},
 				},
 				{
 					Name:     "uploadedParts",
 					TypeName: "any",
 					Index:    1,
+					Span: Span{
+						Start: 50,
+						End:   72,
+					},
 				},
 			}),
 		},
 	}
 	for _, tc := range testCases {
-		require.True(t, re.MatchString(tc.input))
+		template, errs := ParseTemplate(tc.input)
+		require.Empty(t, errs, tc.input)
 		var builders []GoStructFieldBuilder
-		for i, matches := range re.FindAllStringSubmatch(tc.input, -1) {
-			builders = append(builders, NewFieldBuilder(i, matches))
+		for i, node := range template.Interpolations() {
+			builders = append(builders, NewFieldBuilder(i, node))
 		}
 		tc.builders.Equal(t, builders)
 	}
 }
+
+func TestSqlfFormat(t *testing.T) {
+	type testCase struct {
+		input  string
+		output autogold.Value
+	}
+	testCases := []testCase{
+		{input: "SELECT 1", output: autogold.Expect("SELECT 1")},
+		{input: "SELECT {{col: string}} from {{foo: string}}", output: autogold.Expect("SELECT %s from %s")},
+		{input: "WHERE x = {{x : *int : %d}} AND y = {{y: any}}", output: autogold.Expect("WHERE x = %d AND y = %s")},
+		{input: "WHERE x LIKE 'a%' AND y % 2 = {{y : int : %d}}", output: autogold.Expect("WHERE x LIKE 'a%%' AND y %% 2 = %d")},
+		{input: "WHERE id IN ({{ids : []int : list : %d}})", output: autogold.Expect("WHERE id IN (%s)")},
+		{input: "WHERE a{{#if b}} AND c{{/if}}", output: autogold.Expect("WHERE a%s")},
+	}
+	for _, tc := range testCases {
+		template, errs := ParseTemplate(tc.input)
+		require.Empty(t, errs, tc.input)
+		format, _ := template.SqlfFormat()
+		tc.output.Equal(t, format)
+	}
+}
+
+func TestSqlfFormatSections(t *testing.T) {
+	input := "WHERE x = {{x: int}}{{#if !all}} AND y LIKE '%a' {{#if z}}AND z = {{z: _}}{{/if}}{{/if}}"
+	template, errs := ParseTemplate(input)
+	require.Empty(t, errs)
+	format, sites := template.SqlfFormat()
+	require.Equal(t, "WHERE x = %s%s", format)
+	require.Len(t, sites, 2)
+	require.Nil(t, sites[0].Section)
+
+	section := sites[1].Section
+	require.Equal(t, strings.Index(input, "{{#if !all}}"), sites[1].Offset)
+	require.Equal(t, "all", section.Name)
+	require.True(t, section.Negated)
+	require.Equal(t, " AND y LIKE '%%a' %s", section.Format)
+	require.Len(t, section.Sites, 1)
+
+	nested := section.Sites[0].Section
+	require.Equal(t, "z", nested.Name)
+	require.False(t, nested.Negated)
+	require.Equal(t, "AND z = %s", nested.Format)
+	require.Equal(t, []InterpolationSite{{Offset: strings.Index(input, "{{z: _}}"), FormatSpec: "%s", TypeName: "_"}}, nested.Sites)
+}
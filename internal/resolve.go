@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// ImportSpec is an import needed by generated code.
+type ImportSpec struct {
+	// Name is the local name of the import, used as the qualifier.
+	Name string
+	Path string
+	// PkgName is the declared name of the imported package.
+	PkgName string
+}
+
+// TypeNameImports returns the imports needed to use typeName in generated
+// code, based on the imports in scope for the file containing pos
+// (or other files in the package, as in ResolveTypeName).
+func TypeNameImports(pass *analysis.Pass, pos token.Pos, typeName string) []ImportSpec {
+	expr, err := parser.ParseExpr(typeName)
+	if err != nil {
+		return nil
+	}
+	var imports []ImportSpec
+	ast.Inspect(expr, func(node ast.Node) bool {
+		selector, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if qualifier, ok := selector.X.(*ast.Ident); ok {
+			if pkgName := lookupPkgName(pass, pos, qualifier.Name); pkgName != nil {
+				imports = append(imports, ImportSpec{
+					Name:    qualifier.Name,
+					Path:    pkgName.Imported().Path(),
+					PkgName: pkgName.Imported().Name(),
+				})
+			}
+		}
+		return false
+	})
+	return imports
+}
+
+func lookupPkgName(pass *analysis.Pass, pos token.Pos, name string) *types.PkgName {
+	positions := []token.Pos{pos}
+	for _, file := range pass.Files {
+		if file.FileStart <= pos && pos < file.FileEnd {
+			continue
+		}
+		positions = append(positions, file.Name.Pos())
+	}
+	for _, pos := range positions {
+		scope := pass.Pkg.Scope().Innermost(pos)
+		if scope == nil {
+			continue
+		}
+		if _, obj := scope.LookupParent(name, pos); obj != nil {
+			if pkgName, ok := obj.(*types.PkgName); ok {
+				return pkgName
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveTypeName type-checks typeName as a type expression in the scope
+// of the file containing pos, returning the resolved type.
+//
+// Since a package qualifier may only be imported by the generated file
+// (or another file in the package), the other files' scopes are tried
+// if the qualifier is not imported in the file containing pos.
+func ResolveTypeName(pass *analysis.Pass, pos token.Pos, typeName string) (types.Type, error) {
+	expr, err := parser.ParseExpr(typeName)
+	if err != nil {
+		return nil, errors.Newf("%q is not a type", typeName)
+	}
+	typ, err := checkTypeExpr(pass.Fset, pass.Pkg, pos, expr)
+	if err == nil || !usesUnknownQualifier(pass, pos, expr) {
+		return typ, err
+	}
+	for _, file := range pass.Files {
+		if file.FileStart <= pos && pos < file.FileEnd {
+			continue
+		}
+		if typ, otherErr := checkTypeExpr(pass.Fset, pass.Pkg, file.Name.Pos(), expr); otherErr == nil {
+			return typ, nil
+		}
+	}
+	return nil, err
+}
+
+// typeCheckMu serializes calls to types.CheckExpr, since files are visited
+// concurrently, and checking an expression marks the imported package
+// names it uses as used, which mutates the shared package.
+var typeCheckMu sync.Mutex
+
+func checkTypeExpr(fset *token.FileSet, pkg *types.Package, pos token.Pos, expr ast.Expr) (types.Type, error) {
+	info := &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+	typeCheckMu.Lock()
+	defer typeCheckMu.Unlock()
+	if err := types.CheckExpr(fset, pkg, pos, expr, info); err != nil {
+		// expr was parsed separately, so the error's position is meaningless.
+		var typeErr types.Error
+		if errors.As(err, &typeErr) {
+			return nil, errors.New(typeErr.Msg)
+		}
+		return nil, err
+	}
+	tv, ok := info.Types[expr]
+	if !ok || !tv.IsType() {
+		return nil, errors.Newf("%s is not a type", types.ExprString(expr))
+	}
+	return tv.Type, nil
+}
+
+// usesUnknownQualifier checks if expr uses a package qualifier
+// which is not in scope at pos.
+func usesUnknownQualifier(pass *analysis.Pass, pos token.Pos, expr ast.Expr) bool {
+	scope := pass.Pkg.Scope().Innermost(pos)
+	unknown := false
+	ast.Inspect(expr, func(node ast.Node) bool {
+		selector, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if qualifier, ok := selector.X.(*ast.Ident); ok && scope != nil {
+			if _, obj := scope.LookupParent(qualifier.Name, pos); obj == nil {
+				unknown = true
+			}
+		}
+		return false
+	})
+	return unknown
+}
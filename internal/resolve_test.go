@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/hexops/autogold/v2"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis"
+)
+
+func TestResolveTypeName(t *testing.T) {
+	sources := map[string]string{
+		"a.go": `package p
+
+import "strings"
+
+var _ strings.Builder
+
+type ID int
+
+const aQuery = ""
+`,
+		"a_query_gen.go": `package p
+
+import "strconv"
+
+var _ = strconv.Itoa
+`,
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range []string{"a.go", "a_query_gen.go"} {
+		file, err := parser.ParseFile(fset, name, sources[name], 0)
+		require.NoError(t, err)
+		files = append(files, file)
+	}
+	pkg, err := (&types.Config{Importer: importer.Default()}).Check("p", fset, files, nil)
+	require.NoError(t, err)
+	pass := &analysis.Pass{Fset: fset, Files: files, Pkg: pkg}
+	pos := files[0].Scope.Lookup("aQuery").Pos()
+
+	type testCase struct {
+		typeName string
+		result   autogold.Value
+	}
+	testCases := []testCase{
+		{typeName: "int", result: autogold.Expect("int")},
+		{typeName: "[]*ID", result: autogold.Expect("[]*p.ID")},
+		{typeName: "map[string]strings.Builder", result: autogold.Expect("map[string]strings.Builder")},
+		{typeName: "strconv.NumError", result: autogold.Expect("strconv.NumError")},
+		{typeName: "strings.Buildr", result: autogold.Expect("error: undefined: strings.Buildr")},
+		{typeName: "fmt.Stringer", result: autogold.Expect("error: undefined: fmt")},
+		{typeName: "aQuery", result: autogold.Expect("error: aQuery is not a type")},
+	}
+	for _, tc := range testCases {
+		typ, err := ResolveTypeName(pass, pos, tc.typeName)
+		if err != nil {
+			tc.result.Equal(t, "error: "+err.Error())
+		} else {
+			tc.result.Equal(t, typ.String())
+		}
+	}
+}
@@ -0,0 +1,749 @@
+package internal
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// The interpolation grammar is:
+//
+//	interpolation = "{{" ident ":" type { ":" option } "}}"
+//	type          = Go type expression | "_" | fragment
+//	fragment      = "fragment" [ "(" [ ident "." ] ident ")" ]
+//	option        = formatSpec | mode | join | empty
+//	formatSpec    = "%" verb, e.g. %d
+//	mode          = "list" | "array"
+//	join          = "join" string
+//	empty         = "empty" string
+//	string        = Go interpreted string literal, e.g. " AND "
+//
+// Each kind of option may be given at most once, and fragments don't
+// accept any options. join implies the list mode, and empty requires it.
+//
+// Conditional sections, which may be nested, are written as:
+//
+//	section       = "{{#if" [ "!" ] ident "}}" text "{{/if}}"
+//
+// Whitespace is allowed between all tokens.
+//
+// A "{{" only starts an interpolation if it is followed by an identifier
+// and then ":", or by an identifier and then "}}" or another identifier,
+// which are reported as near-misses like {{x}} or {{x int}}. It starts
+// a section tag if it is followed by "#" or "/" and an identifier. Any
+// deviation from the grammar after that is reported as a TemplateError.
+// Any other "{{" is literal text, so that SQL like '{{a,b},{c,d}}'
+// (a 2-D array literal) keeps working.
+//
+// A "\{{" always stands for a literal "{{", e.g. in a comment like
+// -- \{{ not an interpolation }}.
+
+// Span is a half-open range [Start, End) of byte offsets in a template's text.
+type Span struct {
+	Start int
+	End   int
+}
+
+// Template is the parsed form of a query's text.
+type Template struct {
+	Text string
+	// Nodes alternate between literal text and interpolations or
+	// sections, covering Text completely.
+	Nodes []TemplateNode
+}
+
+// TemplateNode is a *TextNode, an *EscapeNode, an *InterpolationNode
+// or a *SectionNode.
+type TemplateNode interface {
+	NodeSpan() Span
+}
+
+// TextNode is a span of literal query text.
+type TextNode struct {
+	Span
+	Text string
+}
+
+// EscapeNode is an escaped "\{{", which stands for a literal "{{".
+type EscapeNode struct {
+	Span
+}
+
+// InterpolationMode changes how the value of an interpolation is
+// turned into SQL.
+type InterpolationMode string
+
+const (
+	// ModeValue formats the value as a single argument. It is the default.
+	ModeValue InterpolationMode = ""
+	// ModeList expands a slice into a comma-separated list with one
+	// argument per element, e.g. for IN (...) or VALUES (...).
+	// The format specifier applies to each element.
+	ModeList InterpolationMode = "list"
+	// ModeArray passes a slice as a single Postgres array argument,
+	// e.g. for = ANY(...). It doesn't allow a format specifier.
+	ModeArray InterpolationMode = "array"
+)
+
+// DefaultListSeparator is the Separator for ModeList without join.
+const DefaultListSeparator = ", "
+
+var interpolationModes = []InterpolationMode{ModeList, ModeArray}
+
+// InterpolationNode is a well-formed {{ ident : type : %spec }} occurrence.
+type InterpolationNode struct {
+	// Span covers everything from the opening {{ to the closing }}.
+	Span
+	Name     string
+	NameSpan Span
+	// TypeName is normalized, e.g. "[] int" becomes "[]int".
+	TypeName string
+	TypeSpan Span
+	// Fragment is set for fragment slots like {{cond : fragment}}.
+	Fragment bool
+	// FragmentRef is the query constant in fragment(ref), if any.
+	FragmentRef string
+	// FormatSpec is empty if not specified.
+	FormatSpec     string
+	FormatSpecSpan Span
+	// Mode is ModeValue if not specified.
+	Mode     InterpolationMode
+	ModeSpan Span
+	// Separator is the text between elements in ModeList,
+	// DefaultListSeparator unless specified with join.
+	Separator string
+	// Empty is the text used in place of an empty list, from the empty
+	// option. If nil, an empty list is an error at runtime.
+	Empty *string
+}
+
+// SectionNode is a conditional section like {{#if cond}} ... {{/if}},
+// which is only included in the query if the bool field cond is true,
+// or false for {{#if !cond}}.
+type SectionNode struct {
+	// Span covers everything from the opening {{#if to the closing {{/if}}.
+	Span
+	Name     string
+	NameSpan Span
+	Negated  bool
+	// OpenSpan and CloseSpan cover the opening {{#if cond}}
+	// and the closing {{/if}}.
+	OpenSpan  Span
+	CloseSpan Span
+	// Nodes are the contents of the section, as in Template.
+	Nodes []TemplateNode
+}
+
+func (s Span) NodeSpan() Span {
+	return s
+}
+
+var _ TemplateNode = &TextNode{}
+var _ TemplateNode = &EscapeNode{}
+var _ TemplateNode = &InterpolationNode{}
+var _ TemplateNode = &SectionNode{}
+
+// TemplateError describes a malformed interpolation.
+type TemplateError struct {
+	Span
+	Message string
+}
+
+var _ error = &TemplateError{}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("offset %d: %s", e.Start, e.Message)
+}
+
+// Interpolations returns the interpolation nodes in the order they occur,
+// including those in sections.
+func (t *Template) Interpolations() []*InterpolationNode {
+	var nodes []*InterpolationNode
+	for _, node := range allNodes(t.Nodes) {
+		if interp, ok := node.(*InterpolationNode); ok {
+			nodes = append(nodes, interp)
+		}
+	}
+	return nodes
+}
+
+// Sections returns the section nodes in the order they occur,
+// with enclosing sections before the sections they contain.
+func (t *Template) Sections() []*SectionNode {
+	var nodes []*SectionNode
+	for _, node := range allNodes(t.Nodes) {
+		if section, ok := node.(*SectionNode); ok {
+			nodes = append(nodes, section)
+		}
+	}
+	return nodes
+}
+
+// allNodes returns nodes along with the contents of sections,
+// in the order they occur.
+func allNodes(nodes []TemplateNode) []TemplateNode {
+	var all []TemplateNode
+	for _, node := range nodes {
+		all = append(all, node)
+		if section, ok := node.(*SectionNode); ok {
+			all = append(all, allNodes(section.Nodes)...)
+		}
+	}
+	return all
+}
+
+// ParseTemplate parses the interpolations in text.
+//
+// Parsing always succeeds; malformed interpolations are reported as errors
+// and are kept as literal text in the returned Template.
+func ParseTemplate(text string) (*Template, []*TemplateError) {
+	p := templateParser{text: text}
+	p.parse()
+	return &Template{Text: text, Nodes: p.nodes}, p.errs
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenColon
+	tokenFormatSpec
+	tokenClose // }}
+	// tokenString is a double-quoted string, which may be unterminated.
+	tokenString
+	// tokenOther is any other punctuation or literal which may occur in
+	// a type expression, such as *, [, ], ., digits etc.
+	tokenOther
+)
+
+type templateToken struct {
+	kind tokenKind
+	Span
+}
+
+// templateLexer tokenizes the contents of a single interpolation.
+type templateLexer struct {
+	text string
+	pos  int
+}
+
+func (l *templateLexer) skipSpace() {
+	for l.pos < len(l.text) {
+		r, size := utf8.DecodeRuneInString(l.text[l.pos:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		l.pos += size
+	}
+}
+
+// next returns the next token. A "{{" is returned as tokenEOF, since
+// an interpolation cannot contain another one.
+func (l *templateLexer) next() templateToken {
+	l.skipSpace()
+	start := l.pos
+	if l.pos >= len(l.text) || strings.HasPrefix(l.text[l.pos:], "{{") {
+		return templateToken{tokenEOF, Span{start, start}}
+	}
+	if strings.HasPrefix(l.text[l.pos:], "}}") {
+		l.pos += 2
+		return templateToken{tokenClose, Span{start, l.pos}}
+	}
+	r, size := utf8.DecodeRuneInString(l.text[l.pos:])
+	switch {
+	case isIdentStart(r):
+		l.pos += size
+		for l.pos < len(l.text) {
+			r, size := utf8.DecodeRuneInString(l.text[l.pos:])
+			if !isIdentStart(r) && !unicode.IsDigit(r) {
+				break
+			}
+			l.pos += size
+		}
+		return templateToken{tokenIdent, Span{start, l.pos}}
+	case r == ':':
+		l.pos += size
+		return templateToken{tokenColon, Span{start, l.pos}}
+	case r == '%':
+		l.pos += size
+		for l.pos < len(l.text) {
+			r, size := utf8.DecodeRuneInString(l.text[l.pos:])
+			if unicode.IsSpace(r) || r == '}' || r == ':' {
+				break
+			}
+			l.pos += size
+		}
+		return templateToken{tokenFormatSpec, Span{start, l.pos}}
+	case r == '"':
+		l.pos += size
+		for l.pos < len(l.text) {
+			switch l.text[l.pos] {
+			case '\\':
+				l.pos = min(l.pos+2, len(l.text))
+				continue
+			case '"':
+				l.pos++
+				return templateToken{tokenString, Span{start, l.pos}}
+			}
+			l.pos++
+		}
+		return templateToken{tokenString, Span{start, l.pos}}
+	default:
+		l.pos += size
+		return templateToken{tokenOther, Span{start, l.pos}}
+	}
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+type templateParser struct {
+	text string
+	// textStart is the start of the pending TextNode.
+	textStart int
+	// nodes are the nodes of the innermost open section,
+	// or the template if there are no open sections.
+	nodes []TemplateNode
+	// sections are the open sections, innermost last.
+	sections []openSection
+	errs     []*TemplateError
+}
+
+type openSection struct {
+	node *SectionNode
+	// outerNodes are the nodes before the section in the enclosing
+	// section or template.
+	outerNodes []TemplateNode
+}
+
+func (p *templateParser) parse() {
+	pos := 0
+	for {
+		i := strings.Index(p.text[pos:], "{{")
+		if i < 0 {
+			break
+		}
+		open := pos + i
+		if open > p.textStart && p.text[open-1] == '\\' {
+			p.flushText(open - 1)
+			p.nodes = append(p.nodes, &EscapeNode{Span{open - 1, open + 2}})
+			p.textStart = open + 2
+			pos = open + 2
+			continue
+		}
+		if isSectionTag(p.text[open:]) {
+			pos = p.parseSectionTag(open)
+			continue
+		}
+		node, end, err := p.parseInterpolation(open)
+		if err != nil {
+			p.errs = append(p.errs, err)
+		}
+		if node == nil {
+			// Keep the text as literal text and continue scanning.
+			pos = end
+			continue
+		}
+		p.flushText(open)
+		p.nodes = append(p.nodes, node)
+		p.textStart = end
+		pos = end
+	}
+	p.flushText(len(p.text))
+
+	// Keep the opening tags of unclosed sections as literal text,
+	// along with their contents.
+	for _, section := range p.sections {
+		p.errs = append(p.errs, &TemplateError{section.node.OpenSpan,
+			fmt.Sprintf("unclosed section for %s; expected {{/if}}", section.node.Name)})
+	}
+	for len(p.sections) > 0 {
+		section := p.sections[len(p.sections)-1]
+		p.sections = p.sections[:len(p.sections)-1]
+		nodes := p.appendText(section.outerNodes, section.node.OpenSpan)
+		for _, node := range p.nodes {
+			if text, ok := node.(*TextNode); ok {
+				nodes = p.appendText(nodes, text.Span)
+			} else {
+				nodes = append(nodes, node)
+			}
+		}
+		p.nodes = nodes
+	}
+}
+
+func (p *templateParser) flushText(end int) {
+	if p.textStart < end {
+		p.nodes = p.appendText(p.nodes, Span{p.textStart, end})
+	}
+	p.textStart = end
+}
+
+// appendText appends a TextNode for span to nodes, merging it with
+// the last node if that's adjacent text.
+func (p *templateParser) appendText(nodes []TemplateNode, span Span) []TemplateNode {
+	if len(nodes) > 0 {
+		if last, ok := nodes[len(nodes)-1].(*TextNode); ok && last.End == span.Start {
+			last.End = span.End
+			last.Text = p.text[last.Start:last.End]
+			return nodes
+		}
+	}
+	return append(nodes, &TextNode{span, p.text[span.Start:span.End]})
+}
+
+// resumeAfter returns the offset to continue scanning from after
+// a malformed interpolation or tag starting at open: after the closing
+// }} if it is otherwise terminated, so that we don't report cascading
+// errors, and right after the "{{" otherwise.
+func (p *templateParser) resumeAfter(open int) int {
+	if close := strings.Index(p.text[open+2:], "}}"); close >= 0 {
+		nextOpen := strings.Index(p.text[open+2:], "{{")
+		if nextOpen < 0 || close < nextOpen {
+			return open + 2 + close + 2
+		}
+	}
+	return open + 2
+}
+
+// isSectionTag reports whether text starts with "{{#" or "{{/"
+// followed by an identifier.
+func isSectionTag(text string) bool {
+	if !strings.HasPrefix(text, "{{#") && !strings.HasPrefix(text, "{{/") {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(text[3:])
+	return isIdentStart(r)
+}
+
+// parseSectionTag parses the {{#if cond}} or {{/if}} tag at offset open,
+// opening or closing a section, and returns the offset to continue
+// scanning from. Malformed tags are reported and kept as literal text.
+func (p *templateParser) parseSectionTag(open int) int {
+	lexer := templateLexer{text: p.text, pos: open + 3}
+	fail := func(at Span, format string, args ...any) int {
+		p.errs = append(p.errs, &TemplateError{Span{open, at.End}, fmt.Sprintf(format, args...)})
+		return p.resumeAfter(open)
+	}
+	closing := p.text[open+2] == '/'
+	keywordTok := lexer.next()
+	if keyword := p.text[keywordTok.Start:keywordTok.End]; keyword != "if" {
+		if closing {
+			return fail(keywordTok.Span, "unknown section end {{/%s}}; expected {{/if}}", keyword)
+		}
+		return fail(keywordTok.Span, "unknown section {{#%s}}; expected {{#if cond}} or {{#if !cond}}", keyword)
+	}
+
+	if closing {
+		if tok := lexer.next(); tok.kind != tokenClose {
+			return fail(tok.Span, "expected '}}' to close {{/if}} but found %q", p.text[tok.Start:tok.End])
+		}
+		if len(p.sections) == 0 {
+			return fail(Span{open, lexer.pos}, "{{/if}} without a matching {{#if}}")
+		}
+		p.flushText(open)
+		section := p.sections[len(p.sections)-1]
+		p.sections = p.sections[:len(p.sections)-1]
+		section.node.End = lexer.pos
+		section.node.CloseSpan = Span{open, lexer.pos}
+		section.node.Nodes = p.nodes
+		p.nodes = append(section.outerNodes, section.node)
+		p.textStart = lexer.pos
+		return lexer.pos
+	}
+
+	node := &SectionNode{}
+	nameTok := lexer.next()
+	if nameTok.kind == tokenOther && p.text[nameTok.Start:nameTok.End] == "!" {
+		node.Negated = true
+		nameTok = lexer.next()
+	}
+	if nameTok.kind != tokenIdent {
+		return fail(nameTok.Span, "expected field name after {{#if but found %q", p.text[nameTok.Start:nameTok.End])
+	}
+	node.Name = p.text[nameTok.Start:nameTok.End]
+	node.NameSpan = nameTok.Span
+	if tok := lexer.next(); tok.kind != tokenClose {
+		return fail(tok.Span, "expected '}}' to close {{#if %s}} but found %q", node.Name, p.text[tok.Start:tok.End])
+	}
+	node.Span = Span{open, lexer.pos}
+	node.OpenSpan = node.Span
+	p.flushText(open)
+	p.sections = append(p.sections, openSection{node, p.nodes})
+	p.nodes = nil
+	p.textStart = lexer.pos
+	return lexer.pos
+}
+
+// parseInterpolation attempts to parse an interpolation starting at the
+// "{{" at offset open.
+//
+// On success, returns the node and the offset just past the closing "}}".
+// Otherwise, returns a nil node, the offset to continue scanning from,
+// and an error if the text looked like an attempted interpolation.
+func (p *templateParser) parseInterpolation(open int) (*InterpolationNode, int, *TemplateError) {
+	lexer := templateLexer{text: p.text, pos: open + 2}
+	// On failure, resume after the closing }} if the interpolation
+	// is otherwise terminated, so that we don't report cascading errors.
+	fail := func(at Span, format string, args ...any) (*InterpolationNode, int, *TemplateError) {
+		return nil, p.resumeAfter(open), &TemplateError{Span{open, at.End}, fmt.Sprintf(format, args...)}
+	}
+
+	nameTok := lexer.next()
+	switch {
+	case nameTok.kind == tokenIdent:
+	case nameTok.kind == tokenClose:
+		return fail(nameTok.Span, "empty interpolation")
+	case strings.HasPrefix(p.text[nameTok.Start:], "."):
+		return fail(nameTok.Span, "text/template syntax is not supported; use {{fieldName : type}}")
+	default:
+		// Not an interpolation, e.g. a Postgres array literal.
+		// Resume right after the first '{' in case of "{{{".
+		return nil, open + 1, nil
+	}
+	node := &InterpolationNode{Name: p.text[nameTok.Start:nameTok.End], NameSpan: nameTok.Span}
+
+	switch tok := lexer.next(); tok.kind {
+	case tokenColon:
+	case tokenClose, tokenIdent:
+		return fail(tok.Span, "expected ':' after field name %s but found %q",
+			node.Name, p.text[tok.Start:tok.End])
+	default:
+		// Not an interpolation either, e.g. '{{a,b},{c,d}}'.
+		return nil, open + 1, nil
+	}
+
+	// The type extends until the next ':' or '}}'.
+	var tok templateToken
+	typeSpan := Span{-1, -1}
+	for {
+		tok = lexer.next()
+		if tok.kind == tokenColon || tok.kind == tokenClose || tok.kind == tokenEOF {
+			break
+		}
+		if typeSpan.Start < 0 {
+			typeSpan.Start = tok.Start
+		}
+		typeSpan.End = tok.End
+	}
+	if tok.kind == tokenEOF {
+		return fail(tok.Span, "unclosed interpolation for %s", node.Name)
+	}
+	if typeSpan.Start < 0 {
+		return fail(tok.Span, "missing type for field %s", node.Name)
+	}
+	typeText := p.text[typeSpan.Start:typeSpan.End]
+	if ref, isFragment, err := parseFragmentType(typeText); isFragment {
+		if err != nil {
+			return fail(typeSpan, "invalid fragment for field %s: %v", node.Name, err)
+		}
+		node.Fragment = true
+		node.FragmentRef = ref
+		node.TypeName = "fragment"
+		if ref != "" {
+			node.TypeName = "fragment(" + ref + ")"
+		}
+	} else {
+		typeName, err := normalizeTypeName(typeText)
+		if err != nil {
+			return fail(typeSpan, "invalid type for field %s: %v", node.Name, err)
+		}
+		node.TypeName = typeName
+	}
+	node.TypeSpan = typeSpan
+
+	var separator *string
+	var joinSpan, emptySpan Span
+	for tok.kind == tokenColon {
+		optionTok := lexer.next()
+		option := p.text[optionTok.Start:optionTok.End]
+		switch optionTok.kind {
+		case tokenFormatSpec:
+			if node.FormatSpec != "" {
+				return fail(optionTok.Span, "duplicate format specifier %s for field %s", option, node.Name)
+			}
+			if !formatSpecRegex.MatchString(option) {
+				return fail(optionTok.Span, "invalid format specifier %s for field %s; "+
+					"expected a single verb like %%d without positional arguments", option, node.Name)
+			}
+			node.FormatSpec = option
+			node.FormatSpecSpan = optionTok.Span
+		case tokenIdent:
+			switch {
+			case option == "join" || option == "empty":
+				textTok := lexer.next()
+				if textTok.kind != tokenString {
+					return fail(textTok.Span, "expected a quoted string like \" AND \" after %s for field %s",
+						option, node.Name)
+				}
+				text, err := strconv.Unquote(p.text[textTok.Start:textTok.End])
+				if err != nil {
+					return fail(textTok.Span, "invalid string %s after %s for field %s",
+						p.text[textTok.Start:textTok.End], option, node.Name)
+				}
+				target, targetSpan := &separator, &joinSpan
+				if option == "empty" {
+					target, targetSpan = &node.Empty, &emptySpan
+				}
+				if *target != nil {
+					return fail(optionTok.Span, "duplicate option %s for field %s", option, node.Name)
+				}
+				*target = &text
+				*targetSpan = Span{optionTok.Start, textTok.End}
+			case !slices.Contains(interpolationModes, InterpolationMode(option)):
+				return fail(optionTok.Span, "unknown option %q for field %s; "+
+					"expected a format specifier like %%d, join, empty or one of the modes %s",
+					option, node.Name, joinModes())
+			case node.Mode != ModeValue:
+				return fail(optionTok.Span, "duplicate mode %s for field %s", option, node.Name)
+			default:
+				node.Mode = InterpolationMode(option)
+				node.ModeSpan = optionTok.Span
+			}
+		case tokenEOF:
+			return fail(optionTok.Span, "unclosed interpolation for %s", node.Name)
+		default:
+			return fail(optionTok.Span, "expected format specifier like %%d or mode for field %s but found %q",
+				node.Name, option)
+		}
+		tok = lexer.next()
+	}
+	if separator != nil {
+		if node.Mode == ModeArray {
+			return fail(joinSpan, "join cannot be used with %s mode for field %s", node.Mode, node.Name)
+		}
+		if node.Mode == ModeValue {
+			node.Mode = ModeList
+			node.ModeSpan = joinSpan
+		}
+	}
+	if node.Fragment && (node.FormatSpec != "" || node.Mode != ModeValue) {
+		optionSpan := node.FormatSpecSpan
+		if node.Mode != ModeValue {
+			optionSpan = node.ModeSpan
+		}
+		return fail(optionSpan, "fragment %s cannot have a format specifier or mode", node.Name)
+	}
+	if node.Mode == ModeArray && node.FormatSpec != "" {
+		return fail(node.FormatSpecSpan, "format specifier %s cannot be used with %s mode for field %s",
+			node.FormatSpec, node.Mode, node.Name)
+	}
+	if node.Empty != nil && node.Mode != ModeList {
+		return fail(emptySpan, "empty requires list mode or join for field %s", node.Name)
+	}
+	if node.Mode == ModeList {
+		node.Separator = DefaultListSeparator
+		if separator != nil {
+			node.Separator = *separator
+		}
+	}
+
+	if tok.kind != tokenClose {
+		if tok.kind == tokenEOF {
+			return fail(tok.Span, "unclosed interpolation for %s", node.Name)
+		}
+		return fail(tok.Span, "expected '}}' to close interpolation for %s but found %q",
+			node.Name, p.text[tok.Start:tok.End])
+	}
+	node.Span = Span{open, tok.End}
+	return node, tok.End, nil
+}
+
+func joinModes() string {
+	modes := make([]string, len(interpolationModes))
+	for i, mode := range interpolationModes {
+		modes[i] = string(mode)
+	}
+	return strings.Join(modes, ", ")
+}
+
+// parseFragmentType reports whether typeName is a fragment type, i.e.
+// "fragment" or "fragment(ref)", returning the normalized ref, if any.
+func parseFragmentType(typeName string) (ref string, isFragment bool, _ error) {
+	expr, err := parser.ParseExpr(typeName)
+	if err != nil {
+		return "", false, nil
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return "", expr.Name == "fragment", nil
+	case *ast.CallExpr:
+		if fun, ok := expr.Fun.(*ast.Ident); !ok || fun.Name != "fragment" {
+			return "", false, nil
+		}
+		if len(expr.Args) == 1 && expr.Ellipsis == token.NoPos && isConstRef(expr.Args[0]) {
+			return types.ExprString(expr.Args[0]), true, nil
+		}
+		return "", true, errors.New("expected a query constant like fragment(someQueryFragment) " +
+			"or fragment(pkg.SomeQueryFragment)")
+	default:
+		return "", false, nil
+	}
+}
+
+func isConstRef(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		_, ok := expr.X.(*ast.Ident)
+		return ok
+	default:
+		return false
+	}
+}
+
+// normalizeTypeName checks that typeName is a valid Go type expression
+// (or _), and returns it in canonical form.
+func normalizeTypeName(typeName string) (string, error) {
+	expr, err := parser.ParseExpr(typeName)
+	if err != nil || !isTypeExpr(expr) {
+		return "", errors.Newf("%q is not a type", typeName)
+	}
+	return types.ExprString(expr), nil
+}
+
+func isTypeExpr(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		_, ok := expr.X.(*ast.Ident)
+		return ok
+	case *ast.StarExpr:
+		return isTypeExpr(expr.X)
+	case *ast.ArrayType:
+		return isTypeExpr(expr.Elt)
+	case *ast.MapType:
+		return isTypeExpr(expr.Key) && isTypeExpr(expr.Value)
+	case *ast.InterfaceType, *ast.StructType, *ast.FuncType, *ast.ChanType:
+		return true
+	case *ast.IndexExpr:
+		return isTypeExpr(expr.X) && isTypeExpr(expr.Index)
+	case *ast.IndexListExpr:
+		if !isTypeExpr(expr.X) {
+			return false
+		}
+		for _, index := range expr.Indices {
+			if !isTypeExpr(index) {
+				return false
+			}
+		}
+		return true
+	case *ast.ParenExpr:
+		return isTypeExpr(expr.X)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hexops/autogold/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTemplate(t *testing.T) {
+	type testCase struct {
+		input  string
+		errors autogold.Value
+	}
+	testCases := []testCase{
+		{input: "SELECT '{{1,2},{3,4}}'::int[][]", errors: autogold.Expect([]string{})},
+		{input: "SELECT '{{a,b},{c,d}}'::text[][]", errors: autogold.Expect([]string{})},
+		{input: "SELECT 1 -- {{x", errors: autogold.Expect([]string{})},
+		{input: "SELECT 1 -- \\{{ x }} and \\{{y int}}", errors: autogold.Expect([]string{})},
+		{input: "{{{x : int}}}", errors: autogold.Expect([]string{})},
+		{input: "{{ x int }}", errors: autogold.Expect([]string{`offset 0: expected ':' after field name x but found "int"`})},
+		{input: "{{x:}}", errors: autogold.Expect([]string{"offset 0: missing type for field x"})},
+		{input: "WHERE a = {{x: int", errors: autogold.Expect([]string{"offset 10: unclosed interpolation for x"})},
+		{input: "{{x: int}} AND {{y: string", errors: autogold.Expect([]string{"offset 15: unclosed interpolation for y"})},
+		{input: "{{x: int AND {{y: string}}", errors: autogold.Expect([]string{"offset 0: unclosed interpolation for x"})},
+		{input: "{{}}", errors: autogold.Expect([]string{"offset 0: empty interpolation"})},
+		{input: "{{.x}}", errors: autogold.Expect([]string{"offset 0: text/template syntax is not supported; use {{fieldName : type}}"})},
+		{input: "{{x: 1 + 2}}", errors: autogold.Expect([]string{`offset 0: invalid type for field x: "1 + 2" is not a type`})},
+		{input: "{{x: int: d}}", errors: autogold.Expect([]string{`offset 0: unknown option "d" for field x; expected a format specifier like %d, join, empty or one of the modes list, array`})},
+		{input: "{{x: int: 'd'}}", errors: autogold.Expect([]string{`offset 0: expected format specifier like %d or mode for field x but found "'"`})},
+		{input: "{{x: []int: list: %d}}", errors: autogold.Expect([]string{})},
+		{input: "{{x: []int: %d : list}}", errors: autogold.Expect([]string{})},
+		{input: "{{x: []int: array}}", errors: autogold.Expect([]string{})},
+		{input: "{{x: []int: array: %d}}", errors: autogold.Expect([]string{"offset 0: format specifier %d cannot be used with array mode for field x"})},
+		{input: "{{x: []int: list: array}}", errors: autogold.Expect([]string{"offset 0: duplicate mode array for field x"})},
+		{input: "{{c: fragment}} {{d: fragment(pkg.SomeQuery)}}", errors: autogold.Expect([]string{})},
+		{input: "{{c: fragment(1)}}", errors: autogold.Expect([]string{
+			"offset 0: invalid fragment for field c: expected a query constant like fragment(someQueryFragment) or fragment(pkg.SomeQueryFragment)",
+		})},
+		{input: "{{c: fragment: list}}", errors: autogold.Expect([]string{"offset 0: fragment c cannot have a format specifier or mode"})},
+		{input: `{{c: []*sqlf.Query: join " AND ": empty "TRUE"}}`, errors: autogold.Expect([]string{})},
+		{input: `{{x: []int: list: join "}}": %d}} {{y: []int: %d}}`, errors: autogold.Expect([]string{})},
+		{input: "{{c: []*sqlf.Query: join AND}}", errors: autogold.Expect([]string{`offset 0: expected a quoted string like " AND " after join for field c`})},
+		{input: `{{c: []*sqlf.Query: join " AND }}`, errors: autogold.Expect([]string{`offset 0: invalid string " AND }} after join for field c`})},
+		{input: `{{c: []*sqlf.Query: join "a": join "b"}}`, errors: autogold.Expect([]string{"offset 0: duplicate option join for field c"})},
+		{input: `{{x: []int: array: join ","}}`, errors: autogold.Expect([]string{"offset 0: join cannot be used with array mode for field x"})},
+		{input: `{{x: []int: empty "NULL"}}`, errors: autogold.Expect([]string{"offset 0: empty requires list mode or join for field x"})},
+		{input: `{{c: fragment: join ","}}`, errors: autogold.Expect([]string{"offset 0: fragment c cannot have a format specifier or mode"})},
+		{input: "{{#if a}}x{{#if !b}}{{c: int}}{{/if}}{{/if}}", errors: autogold.Expect([]string{})},
+		{input: "{{#if a}} {{#if b}}", errors: autogold.Expect([]string{
+			"offset 0: unclosed section for a; expected {{/if}}",
+			"offset 10: unclosed section for b; expected {{/if}}",
+		})},
+		{input: "x {{/if}}", errors: autogold.Expect([]string{"offset 2: {{/if}} without a matching {{#if}}"})},
+		{input: "{{#each xs}}{{/each}}", errors: autogold.Expect([]string{
+			"offset 0: unknown section {{#each}}; expected {{#if cond}} or {{#if !cond}}",
+			"offset 12: unknown section end {{/each}}; expected {{/if}}",
+		})},
+		{input: "{{#if}}", errors: autogold.Expect([]string{`offset 0: expected field name after {{#if but found "}}"`})},
+		{input: "{{#if a b}}{{/if}}", errors: autogold.Expect([]string{
+			`offset 0: expected '}}' to close {{#if a}} but found "b"`,
+			"offset 11: {{/if}} without a matching {{#if}}",
+		})},
+		{input: "{{/if x}}", errors: autogold.Expect([]string{`offset 0: expected '}}' to close {{/if}} but found "x"`})},
+		{input: "SELECT '{{#}}', '{{/ }}'", errors: autogold.Expect([]string{})},
+		{input: "{{x: []int: list: list}}", errors: autogold.Expect([]string{"offset 0: duplicate mode list for field x"})},
+		{input: "{{x: int: %d: %s}}", errors: autogold.Expect([]string{"offset 0: duplicate format specifier %s for field x"})},
+		{input: "{{x: int: %[1]d}}", errors: autogold.Expect([]string{
+			"offset 0: invalid format specifier %[1]d for field x; expected a single verb like %d without positional arguments",
+		})},
+		{input: "{{x: int: %d extra}}", errors: autogold.Expect([]string{`offset 0: expected '}}' to close interpolation for x but found "extra"`})},
+		{input: "{{ x }} {{y : int}} {{z int}}", errors: autogold.Expect([]string{
+			`offset 0: expected ':' after field name x but found "}}"`,
+			`offset 20: expected ':' after field name z but found "int"`,
+		})},
+	}
+	for _, tc := range testCases {
+		_, errs := ParseTemplate(tc.input)
+		messages := []string{}
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+		tc.errors.Equal(t, messages)
+	}
+}
+
+func TestParseTemplateNodes(t *testing.T) {
+	input := "SELECT {{ cols : [] string }} FROM t WHERE id = {{id: *abc.ID : %d}}"
+	template, errs := ParseTemplate(input)
+	require.Empty(t, errs)
+	nodes := template.Interpolations()
+	require.Len(t, nodes, 2)
+
+	require.Equal(t, "cols", nodes[0].Name)
+	require.Equal(t, "[]string", nodes[0].TypeName)
+	require.Equal(t, "[] string", input[nodes[0].TypeSpan.Start:nodes[0].TypeSpan.End])
+	require.Equal(t, "{{ cols : [] string }}", input[nodes[0].Start:nodes[0].End])
+
+	require.Equal(t, "id", input[nodes[1].NameSpan.Start:nodes[1].NameSpan.End])
+	require.Equal(t, "*abc.ID", nodes[1].TypeName)
+	require.Equal(t, "%d", input[nodes[1].FormatSpecSpan.Start:nodes[1].FormatSpecSpan.End])
+	require.Equal(t, ModeValue, nodes[1].Mode)
+
+	input = "WHERE id IN ({{ids : []int : list : %d}})"
+	template, errs = ParseTemplate(input)
+	require.Empty(t, errs)
+	node := template.Interpolations()[0]
+	require.Equal(t, ModeList, node.Mode)
+	require.Equal(t, "list", input[node.ModeSpan.Start:node.ModeSpan.End])
+	require.Equal(t, "%d", node.FormatSpec)
+
+	require.Equal(t, DefaultListSeparator, node.Separator)
+	require.Nil(t, node.Empty)
+
+	input = `WHERE {{conds : []*sqlf.Query : join " AND " : empty "TRUE"}}`
+	template, errs = ParseTemplate(input)
+	require.Empty(t, errs)
+	node = template.Interpolations()[0]
+	require.Equal(t, ModeList, node.Mode)
+	require.Equal(t, `join " AND "`, input[node.ModeSpan.Start:node.ModeSpan.End])
+	require.Equal(t, " AND ", node.Separator)
+	require.Equal(t, "TRUE", *node.Empty)
+
+	input = "WHERE {{cond : fragment( shared.RepoQuery )}} AND {{extra: fragment}}"
+	template, errs = ParseTemplate(input)
+	require.Empty(t, errs)
+	nodes = template.Interpolations()
+	require.True(t, nodes[0].Fragment)
+	require.Equal(t, "shared.RepoQuery", nodes[0].FragmentRef)
+	require.Equal(t, "fragment(shared.RepoQuery)", nodes[0].TypeName)
+	require.True(t, nodes[1].Fragment)
+	require.Empty(t, nodes[1].FragmentRef)
+}
+
+func TestParseTemplateEscapes(t *testing.T) {
+	input := `SELECT '\{{a}}' WHERE {{#if b}}\{{x: int}}{{/if}}`
+	template, errs := ParseTemplate(input)
+	require.Empty(t, errs)
+	require.Empty(t, template.Interpolations())
+	require.Equal(t, &EscapeNode{Span{8, 11}}, template.Nodes[1])
+	format, sites := template.SqlfFormat()
+	require.Equal(t, "SELECT '{{a}}' WHERE %s", format)
+	require.Equal(t, "{{x: int}}", sites[0].Section.Format)
+}
+
+func TestParseTemplateSections(t *testing.T) {
+	input := "WHERE a = 1{{#if !all}} AND b = {{b: int}}{{#if c}} AND c{{/if}}{{/if}} LIMIT 1"
+	template, errs := ParseTemplate(input)
+	require.Empty(t, errs)
+	require.Len(t, template.Nodes, 3)
+	section := template.Nodes[1].(*SectionNode)
+	require.Equal(t, "all", section.Name)
+	require.True(t, section.Negated)
+	require.Equal(t, "{{#if !all}}", input[section.OpenSpan.Start:section.OpenSpan.End])
+	require.True(t, strings.HasSuffix(input[:section.End], "{{/if}}{{/if}}"))
+	require.Len(t, section.Nodes, 3)
+	nested := section.Nodes[2].(*SectionNode)
+	require.Equal(t, "c", nested.Name)
+	require.False(t, nested.Negated)
+
+	require.Equal(t, []*SectionNode{section, nested}, template.Sections())
+	require.Len(t, template.Interpolations(), 1)
+
+	// Unclosed sections are kept as literal text.
+	input = "WHERE {{#if a}}b = {{b: int}}"
+	template, errs = ParseTemplate(input)
+	require.Len(t, errs, 1)
+	require.Len(t, template.Nodes, 2)
+	require.Equal(t, "WHERE {{#if a}}b = ", template.Nodes[0].(*TextNode).Text)
+	require.Empty(t, template.Sections())
+}
+
+func FuzzParseTemplate(f *testing.F) {
+	f.Add("SELECT * FROM {{tableName: string}} WHERE id = {{id : *int : %d}}")
+	f.Add("{{x: int}} {{x: _}} {{ y : map[string][]abc.X }}")
+	f.Add("SELECT '{{1,2}}' {{ x int }} {{x:}} {{x: int")
+	f.Add("{{{{}}}}{{.x}}")
+	f.Add("{{ids : []int : list : %d}} {{x: int: list: list}}")
+	f.Add(`{{c : []*sqlf.Query : join " AND " : empty "TRUE"}} {{d: int: join "\"}}`)
+	f.Add("{{#if a}} {{x: int}} {{#if !b}}{{/if}} {{/if}} {{/if}} {{#if c}}")
+	f.Add(`\{{x: int}} {{a,b}} \\{{{x}} {{x`)
+	f.Fuzz(func(t *testing.T, input string) {
+		template, errs := ParseTemplate(input)
+
+		// Nodes must cover the input exactly, in order,
+		// and so must the contents of sections.
+		var checkNodes func(nodes []TemplateNode, start, end int)
+		checkNodes = func(nodes []TemplateNode, start, end int) {
+			last := start
+			for _, node := range nodes {
+				span := node.NodeSpan()
+				require.Equal(t, last, span.Start)
+				require.Less(t, span.Start, span.End)
+				switch node := node.(type) {
+				case *TextNode:
+					require.Equal(t, input[span.Start:span.End], node.Text)
+				case *EscapeNode:
+					require.Equal(t, `\{{`, input[span.Start:span.End])
+				case *SectionNode:
+					require.Equal(t, span.End, node.CloseSpan.End)
+					checkNodes(node.Nodes, node.OpenSpan.End, node.CloseSpan.Start)
+				}
+				last = span.End
+			}
+			require.Equal(t, end, last)
+		}
+		checkNodes(template.Nodes, 0, len(input))
+
+		for _, node := range template.Interpolations() {
+			require.True(t, strings.HasPrefix(input[node.Start:], "{{"))
+			require.True(t, strings.HasSuffix(input[:node.End], "}}"))
+			require.Equal(t, node.Name, input[node.NameSpan.Start:node.NameSpan.End])
+			require.NotEmpty(t, node.TypeName)
+		}
+		for _, err := range errs {
+			require.True(t, 0 <= err.Start && err.Start <= err.End && err.End <= len(input))
+			require.True(t, strings.HasPrefix(input[err.Start:], "{{"))
+		}
+	})
+}
@@ -1,19 +1,25 @@
 package internal
 
 import (
-	"github.com/grafana/regexp"
 	"go/ast"
+	"go/constant"
 	"go/token"
+	"go/types"
+	"strings"
+
 	"golang.org/x/tools/go/analysis"
 
 	"github.com/charmbracelet/log"
+	"github.com/grafana/regexp"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
 )
 
 type QueryGenVisitor struct {
-	pass                *analysis.Pass
-	foldingState        Set[string]
-	perFileDefs         map[string]PosToDefMap
-	substitutionRegex   *regexp.Regexp
+	pass         *analysis.Pass
+	foldingState Set[string]
+	perFileDefs  map[string]PosToDefMap
+	// queryConstNameRegex is nil if only constants marked with
+	// //querygen:query are queries.
 	queryConstNameRegex *regexp.Regexp
 	structFactory       StructFactory
 	logger              *log.Logger
@@ -24,17 +30,22 @@ type QueryGenVisitor struct {
 
 var _ ast.Visitor = &QueryGenVisitor{}
 
-func NewQueryGenVisitor(logger *log.Logger, pass *analysis.Pass) *QueryGenVisitor {
-	return &QueryGenVisitor{
+func NewQueryGenVisitor(logger *log.Logger, pass *analysis.Pass, settings *Settings) *QueryGenVisitor {
+	queryConstNameRegex := settings.QueryNameRegex
+	if settings.RequireQueryDirective {
+		queryConstNameRegex = nil
+	}
+	visitor := &QueryGenVisitor{
 		pass:                pass,
 		foldingState:        Set[string]{},
 		perFileDefs:         map[string]PosToDefMap{},
-		substitutionRegex:   SubstitutionRegex,
-		queryConstNameRegex: QueryConstNameRegex,
-		structFactory:       StructFactory{pass},
+		queryConstNameRegex: queryConstNameRegex,
+		structFactory:       StructFactory{pass, settings, nil},
 		logger:              logger,
 		ParamStructs:        nil,
 	}
+	visitor.structFactory.Fragments = visitor
+	return visitor
 }
 
 type posToNodeMapperVisitor struct {
@@ -72,13 +83,18 @@ func (q *QueryGenVisitor) Visit(node ast.Node) ast.Visitor {
 			if !ok { // malformed syntax
 				continue
 			}
+			directives := ParseQueryDirectives(q.pass, genDecl, valSpec)
+			if directives.Ignore {
+				continue
+			}
 			for i, ident := range valSpec.Names {
 				if i >= len(valSpec.Values) { // malformed code
 					break
 				}
-				if !q.queryConstNameRegex.MatchString(ident.Name) {
+				if !q.isQueryConst(ident, directives) {
 					continue
 				}
+				typeName := queryTypeName(ident, directives)
 				queryVarName := ident.Name
 				logger := q.logger.With("const", queryVarName)
 				expr := valSpec.Values[i]
@@ -87,17 +103,24 @@ func (q *QueryGenVisitor) Visit(node ast.Node) ast.Visitor {
 					defer q.foldingState.Remove(queryVarName)
 					logger.Debug("trying to fold Query string")
 					if foldedString, ok := q.tryFoldString(expr); ok {
-						logger.Debug("constant-folded Query string", "foldedString", foldedString)
-						goStruct, err := q.structFactory.NewGoStruct(q.substitutionRegex, ident, foldedString)
+						logger.Debug("constant-folded Query string", "foldedString", foldedString.Text)
+						goStruct, err := q.structFactory.NewGoStruct(ident, typeName, foldedString)
 						if err != nil {
 							logger.Error("failed to create struct from query string", "err", err)
 							return
 						}
 						if goStruct != nil {
 							q.ParamStructs = append(q.ParamStructs, *goStruct)
+						} else if directives.Query {
+							q.pass.Reportf(ident.Pos(), "%s is marked with %squery but has no interpolations",
+								ident.Name, directivePrefix)
 						}
 					} else {
 						logger.Debug("failed to fold Query string")
+						if directives.Query {
+							q.pass.Reportf(ident.Pos(), "%s is marked with %squery but is not a constant string",
+								ident.Name, directivePrefix)
+						}
 					}
 				}()
 			}
@@ -107,10 +130,113 @@ func (q *QueryGenVisitor) Visit(node ast.Node) ast.Visitor {
 	return q
 }
 
-func (q *QueryGenVisitor) tryLocateStringForPos(pos token.Pos) (string, bool) {
+// isQueryConst reports whether the constant ident is a query,
+// based on its name and directives.
+func (q *QueryGenVisitor) isQueryConst(ident *ast.Ident, directives QueryDirectives) bool {
+	if directives.Ignore {
+		return false
+	}
+	return directives.Query || (q.queryConstNameRegex != nil && q.queryConstNameRegex.MatchString(ident.Name))
+}
+
+// queryTypeName returns the name of the Vars type for the query constant ident.
+func queryTypeName(ident *ast.Ident, directives QueryDirectives) string {
+	if directives.TypeName != "" {
+		return directives.TypeName
+	}
+	return ident.Name + "Vars"
+}
+
+// FragmentVarsType implements FragmentResolver.
+//
+// Constants from other packages are looked up using their QueryFact.
+// Constants from this package are checked the same way as in Visit,
+// since their structs may not have been generated yet.
+func (q *QueryGenVisitor) FragmentVarsType(pos token.Pos, ref string) (string, []ImportSpec, error) {
+	qualifier, name, isQualified := strings.Cut(ref, ".")
+	if !isQualified {
+		return q.localFragmentVarsType(ref)
+	}
+	pkgName := lookupPkgName(q.pass, pos, qualifier)
+	if pkgName == nil {
+		return "", nil, errors.Newf("undefined: %s", qualifier)
+	}
+	imported := pkgName.Imported()
+	constObj, ok := imported.Scope().Lookup(name).(*types.Const)
+	if !ok || !constObj.Exported() {
+		return "", nil, errors.Newf("%s is not an exported constant", ref)
+	}
+	var fact QueryFact
+	if !q.pass.ImportObjectFact(constObj, &fact) {
+		return "", nil, errors.Newf("%s is not a query with interpolations", ref)
+	}
+	if !token.IsExported(fact.TypeName) {
+		return "", nil, errors.Newf("type %s generated for %s is not exported", fact.TypeName, ref)
+	}
+	spec := ImportSpec{Name: qualifier, Path: imported.Path(), PkgName: imported.Name()}
+	return qualifier + "." + fact.TypeName, []ImportSpec{spec}, nil
+}
+
+func (q *QueryGenVisitor) localFragmentVarsType(name string) (string, []ImportSpec, error) {
+	constObj, ok := q.pass.Pkg.Scope().Lookup(name).(*types.Const)
+	if !ok {
+		return "", nil, errors.Newf("%s is not a constant declared in package %s", name, q.pass.Pkg.Name())
+	}
+	genDecl, valSpec, index := q.findConstSpec(constObj)
+	if valSpec == nil || index >= len(valSpec.Values) {
+		return "", nil, errors.Newf("declaration of %s not found", name)
+	}
+	// Problems with the directives are reported for the constant itself.
+	quietPass := *q.pass
+	quietPass.Report = func(analysis.Diagnostic) {}
+	directives := ParseQueryDirectives(&quietPass, genDecl, valSpec)
+	ident := valSpec.Names[index]
+	if !q.isQueryConst(ident, directives) {
+		return "", nil, errors.Newf("%s is not a query constant", name)
+	}
+	folded, ok := q.tryFoldString(valSpec.Values[index])
+	if !ok {
+		return "", nil, errors.Newf("%s is not a constant string", name)
+	}
+	template, errs := ParseTemplate(folded.Text)
+	if len(errs) != 0 || len(template.Interpolations())+len(template.Sections()) == 0 {
+		return "", nil, errors.Newf("%s is not a query with well-formed interpolations", name)
+	}
+	return queryTypeName(ident, directives), nil, nil
+}
+
+// findConstSpec returns the declaration of the package-level constant obj,
+// along with its index in valSpec.Names.
+func (q *QueryGenVisitor) findConstSpec(obj *types.Const) (*ast.GenDecl, *ast.ValueSpec, int) {
+	for _, file := range q.pass.Files {
+		if obj.Pos() < file.FileStart || file.FileEnd <= obj.Pos() {
+			continue
+		}
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				valSpec, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				for i, ident := range valSpec.Names {
+					if ident.Pos() == obj.Pos() {
+						return genDecl, valSpec, i
+					}
+				}
+			}
+		}
+	}
+	return nil, nil, 0
+}
+
+func (q *QueryGenVisitor) tryLocateStringForPos(pos token.Pos) (FoldedString, bool) {
 	file := q.pass.Fset.File(pos)
 	if file == nil {
-		return "", false
+		return FoldedString{}, false
 	}
 	var astFile *ast.File
 	for _, f := range q.pass.Files {
@@ -121,7 +247,7 @@ func (q *QueryGenVisitor) tryLocateStringForPos(pos token.Pos) (string, bool) {
 	}
 	if astFile == nil {
 		q.pass.Reportf(token.NoPos, "failed to locate astFile for: %v", file.Name())
-		return "", false
+		return FoldedString{}, false
 	}
 	if q.perFileDefs == nil {
 		q.perFileDefs = make(map[string]PosToDefMap)
@@ -136,45 +262,108 @@ func (q *QueryGenVisitor) tryLocateStringForPos(pos token.Pos) (string, bool) {
 	if posDef, ok := fileDefPosMap[pos]; ok {
 		return q.tryFoldString(posDef.value)
 	}
-	return "", false
+	return FoldedString{}, false
 }
 
-func (q *QueryGenVisitor) tryFoldString(expr ast.Expr) (string, bool) {
+// tryFoldString constant-folds expr into a string.
+//
+// String literals, concatenations and references to constants declared
+// in this package are folded syntactically, so that positions in the
+// result map back to the source. Other constant expressions, such as
+// conversions or constants imported from other packages, are folded
+// using the value computed by the type-checker, in which case positions
+// map to the start of the expression. For imported query constants,
+// the QueryFact exported for them is used to type their fields.
+func (q *QueryGenVisitor) tryFoldString(expr ast.Expr) (FoldedString, bool) {
+	if folded, ok := q.tryFoldStringSyntax(expr); ok {
+		return folded, true
+	}
+	if fact := q.importedQueryFact(expr); fact != nil {
+		return FoldImportedQuery(fact, expr.Pos()), true
+	}
+	typeAndValue, ok := q.pass.TypesInfo.Types[expr]
+	if !ok || typeAndValue.Value == nil || typeAndValue.Value.Kind() != constant.String {
+		return FoldedString{}, false
+	}
+	return FoldOpaque(constant.StringVal(typeAndValue.Value), expr.Pos()), true
+}
+
+// importedQueryFact returns the fact for expr if it refers to
+// a query constant from another package.
+func (q *QueryGenVisitor) importedQueryFact(expr ast.Expr) *QueryFact {
+	var ident *ast.Ident
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		ident = expr
+	case *ast.SelectorExpr:
+		ident = expr.Sel
+	default:
+		return nil
+	}
+	constObj, ok := q.pass.TypesInfo.ObjectOf(ident).(*types.Const)
+	if !ok || constObj.Pkg() == q.pass.Pkg {
+		return nil
+	}
+	var fact QueryFact
+	if !q.pass.ImportObjectFact(constObj, &fact) {
+		return nil
+	}
+	return &fact
+}
+
+func (q *QueryGenVisitor) tryFoldStringSyntax(expr ast.Expr) (FoldedString, bool) {
 	switch expr.(type) {
 	case *ast.BasicLit:
 		basicLit := expr.(*ast.BasicLit)
 		if basicLit.Kind != token.STRING {
-			return "", false
+			return FoldedString{}, false
 		}
-		return basicLit.Value, true
+		folded, err := FoldStringLit(q.pass.Fset.File(basicLit.Pos()), basicLit)
+		if err != nil {
+			return FoldedString{}, false
+		}
+		return folded, true
+	case *ast.ParenExpr:
+		return q.tryFoldString(expr.(*ast.ParenExpr).X)
+	case *ast.CallExpr:
+		// Conversions like string(typedQueryConst)
+		callExpr := expr.(*ast.CallExpr)
+		if len(callExpr.Args) != 1 || !q.pass.TypesInfo.Types[callExpr.Fun].IsType() {
+			return FoldedString{}, false
+		}
+		return q.tryFoldString(callExpr.Args[0])
 	case *ast.BinaryExpr:
 		binaryExpr := expr.(*ast.BinaryExpr)
+		if binaryExpr.Op != token.ADD {
+			return FoldedString{}, false
+		}
 		lhs, ok := q.tryFoldString(binaryExpr.X)
 		if !ok {
-			return "", false
+			return FoldedString{}, false
 		}
 		rhs, ok := q.tryFoldString(binaryExpr.Y)
 		if !ok {
-			return "", false
+			return FoldedString{}, false
 		}
-		return lhs + rhs, true
+		return lhs.Concat(rhs), true
 	case *ast.Ident:
 		ident := expr.(*ast.Ident)
 
 		if q.foldingState.Has(ident.Name) {
 			q.logger.Warn("cyclic dependency in constant expression", "ident", ident.Name)
-			return "", false
+			return FoldedString{}, false
 		}
 		q.foldingState.Add(ident.Name)
 		defer q.foldingState.Remove(ident.Name)
 
 		object := q.pass.TypesInfo.ObjectOf(ident)
-		if object == nil {
-			return "", false
+		if object == nil || object.Pkg() != q.pass.Pkg {
+			// Dot-imported constants are handled by the caller.
+			return FoldedString{}, false
 		}
 		pos := object.Pos()
 		return q.tryLocateStringForPos(pos)
 	default:
-		return "", false
+		return FoldedString{}, false
 	}
 }
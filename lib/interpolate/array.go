@@ -0,0 +1,129 @@
+package interpolate
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ArrayArg is the argument for a {{x : []T : array}} interpolation.
+//
+// It encodes the slice as a Postgres array literal, like pq.Array,
+// so that it is sent as a single array parameter, e.g. for = ANY(...).
+// A nil slice is encoded as NULL.
+type ArrayArg struct {
+	// Values is a slice of booleans, integers, floats, strings or []byte,
+	// or pointers to them, where nil pointers are encoded as NULL.
+	Values any
+}
+
+var _ driver.Valuer = ArrayArg{}
+
+// Array creates an ArrayArg from values. Generated FormatArgs methods
+// call it for interpolations using the array mode.
+func Array[T any](values []T) ArrayArg {
+	return ArrayArg{Values: values}
+}
+
+// arrayFromSlice creates an ArrayArg from a slice or array of any type,
+// for FormatArgs which weren't generated by querygen.
+func arrayFromSlice(value any) (driver.Valuer, bool) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		return valuer, true
+	}
+	kind := reflect.ValueOf(value).Kind()
+	if kind != reflect.Slice && kind != reflect.Array {
+		return nil, false
+	}
+	return ArrayArg{Values: value}, true
+}
+
+// Value implements driver.Valuer.
+func (a ArrayArg) Value() (driver.Value, error) {
+	rv := reflect.ValueOf(a.Values)
+	if rv.Kind() == reflect.Slice && rv.IsNil() {
+		return nil, nil
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot encode %T as a Postgres array", a.Values)
+	}
+	var buf strings.Builder
+	buf.WriteByte('{')
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeArrayElem(&buf, rv.Index(i)); err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.String(), nil
+}
+
+func writeArrayElem(buf *strings.Builder, rv reflect.Value) error {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			buf.WriteString("NULL")
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			buf.WriteByte('t')
+		} else {
+			buf.WriteByte('f')
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString(strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		buf.WriteString(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		switch {
+		case math.IsInf(f, 1):
+			buf.WriteString("Infinity")
+		case math.IsInf(f, -1):
+			buf.WriteString("-Infinity")
+		default:
+			buf.WriteString(strconv.FormatFloat(f, 'g', -1, rv.Type().Bits()))
+		}
+	case reflect.String:
+		writeQuotedArrayElem(buf, rv.String())
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported element type %s", rv.Type())
+		}
+		if rv.IsNil() {
+			buf.WriteString("NULL")
+			return nil
+		}
+		// bytea in hex format, with the backslash escaped.
+		buf.WriteString(`"\\x`)
+		buf.WriteString(hex.EncodeToString(rv.Bytes()))
+		buf.WriteByte('"')
+	default:
+		return fmt.Errorf("unsupported element type %s", rv.Type())
+	}
+	return nil
+}
+
+// writeQuotedArrayElem writes s as a double-quoted array element,
+// which is needed for empty strings, NULL, and strings containing
+// delimiters, quotes or whitespace.
+func writeQuotedArrayElem(buf *strings.Builder, s string) {
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(s[i])
+	}
+	buf.WriteByte('"')
+}
@@ -0,0 +1,59 @@
+package interpolate
+
+import (
+	"fmt"
+)
+
+// Fragment is a query built from the vars V of a query fragment,
+// for {{cond : fragment(someQueryFragment)}} interpolations.
+//
+// Unlike a plain *Query, which can be any query, a Fragment can only
+// be created from V using NewFragment, e.g.
+//
+//	interpolate.NewFragment(&someQueryFragmentVars{...})
+type Fragment[V QueryVars] struct {
+	build func() (*Query, error)
+}
+
+// NewFragment creates a Fragment for vars. The query for vars is built
+// along with the query containing the fragment, so errors building it,
+// such as an EmptyListError, are returned by that query's Build method.
+func NewFragment[V interface {
+	QueryVars
+	Build() (*Query, error)
+}](vars V) Fragment[V] {
+	return Fragment[V]{build: vars.Build}
+}
+
+// Query builds the query for the fragment.
+// Returns nil, nil for the zero Fragment.
+func (f Fragment[V]) Query() (*Query, error) {
+	if f.build == nil {
+		return nil, nil
+	}
+	return f.build()
+}
+
+func (f Fragment[V]) fragmentQuery() (*Query, error) {
+	return f.Query()
+}
+
+// fragmentArg is implemented by every instantiation of Fragment.
+type fragmentArg interface {
+	fragmentQuery() (*Query, error)
+}
+
+// UnsetFragmentError is returned when the value for a fragment
+// is a nil *Query or the zero Fragment.
+type UnsetFragmentError struct {
+	// Query is the query text or format string.
+	Query string
+	// Index is the 0-based index of the interpolation.
+	Index int
+}
+
+var _ error = &UnsetFragmentError{}
+
+func (e *UnsetFragmentError) Error() string {
+	return fmt.Sprintf("unset fragment for interpolation %d in query %s", e.Index, abbreviate(e.Query))
+}
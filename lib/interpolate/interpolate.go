@@ -2,14 +2,21 @@ package interpolate
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/keegancsmith/sqlf"
 
 	"github.com/sourcegraph/querygen/internal"
 )
 
+// Query is an alias for *sqlf.Query, so that generated code
+// doesn't need to import sqlf separately.
+type Query = sqlf.Query
+
 type QueryVars interface {
-	// FormatArgs returns 1 element per interpolation
+	// FormatArgs returns 1 element per interpolation,
+	// or per conditional section for the interpolations it contains.
 	FormatArgs() []any
 }
 
@@ -23,21 +30,334 @@ func (e *QueryDoesntUseInterpolationError) Error() string {
 
 // Do creates a sqlf.Query from the given query string and QueryVars.
 //
-// If the query doesn't use interpolation, returns nil, &QueryDoesntUseInterpolationError{}.
+// Each interpolation is replaced by its format specifier, so {{x : int : %d}}
+// is formatted using %d. Interpolations without a format specifier use %s.
+// Interpolations using the list mode, like {{ids : []int : list}},
+// accept a slice (or a ListArg), and are expanded to one bind variable
+// per element, each formatted using the format specifier. The elements
+// are separated by ", ", or the text of a join option, so that
+// {{conds : []*sqlf.Query : join " AND "}} splices in each condition.
+// Interpolations using the array mode, like {{ids : []int : array}},
+// accept a slice, which is passed as a single ArrayArg.
+// Conditional sections, like {{#if cond}} ... {{/if}}, accept a SectionArg,
+// whose Args are checked like those of the query if it's enabled.
+//
+// Since query and q are not tied together statically, Do validates
+// that the number of FormatArgs matches the number of interpolations,
+// and that each value can be formatted using its format specifier.
+//
+// Possible errors:
+//   - If the query has malformed interpolations, &MalformedInterpolationError{}.
+//   - If the query doesn't use interpolation, &QueryDoesntUseInterpolationError{}.
+//   - If the number of FormatArgs is different, &ArgCountMismatchError{}.
+//   - If a value doesn't fit its format specifier, &ArgTypeMismatchError{}.
+//   - If the value for a fragment, or an element of a list of queries or
+//     fragments, isn't a *Query or Fragment, &ArgTypeMismatchError{}.
+//   - If the value for a list interpolation is empty and it doesn't have
+//     an empty option, &EmptyListError{}.
+//   - If a fragment, or an element of a list of fragments, is nil, a nil
+//     *Query or the zero Fragment, &UnsetFragmentError{}.
+//   - If building the query for a Fragment fails, the error from its Build.
 func Do(query string, q QueryVars) (*sqlf.Query, error) {
-	modifiedQuery := internal.SubstitutionRegex.ReplaceAllString(query, "%s")
-	// Just doing a length check instead of a content check is OK
-	// because a query with one or more interpolations must be longer
-	// than the query with replacements.
-	if len(modifiedQuery) == len(query) {
+	return do(query, q, false)
+}
+
+// DoStrict is like Do, but also checks that each value has the type
+// declared by its interpolation, so {{x : int}} doesn't accept an int64,
+// and {{x : string}} doesn't accept an int even though %s formats anything.
+// A nil value is only accepted for pointer and slice types.
+//
+// Only types which can be named without a package are checked: predeclared
+// types like int and string, and pointers to and slices of them. Values for
+// other types, such as time.Time or any, are checked as by Do.
+//
+// If a value doesn't have its declared type, returns nil, &ArgTypeMismatchError{}
+// with the TypeName set.
+func DoStrict(query string, q QueryVars) (*sqlf.Query, error) {
+	return do(query, q, true)
+}
+
+func do(query string, q QueryVars, strict bool) (*sqlf.Query, error) {
+	template, templateErrs := internal.ParseTemplate(query)
+	if len(templateErrs) != 0 {
+		return nil, &MalformedInterpolationError{
+			Query:   query,
+			Offset:  templateErrs[0].Start,
+			Message: templateErrs[0].Message,
+		}
+	}
+	modifiedQuery, sites := template.SqlfFormat()
+	if len(sites) == 0 {
 		return nil, &QueryDoesntUseInterpolationError{}
 	}
-	return sqlf.Sprintf(modifiedQuery, q.FormatArgs()...), nil
+	args, err := checkArgs(query, sites, q.FormatArgs(), strict)
+	if err != nil {
+		return nil, err
+	}
+	args, err = expandArgs(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return sqlf.Sprintf(modifiedQuery, args...), nil
+}
+
+// checkArgs validates args against sites, returning a copy where slices
+// for list and array interpolations are replaced by a ListArg or ArrayArg.
+// The arguments of enabled conditional sections are checked recursively.
+// If strict, values must also have the type declared by the interpolation.
+func checkArgs(query string, sites []internal.InterpolationSite, args []any, strict bool) ([]any, error) {
+	if len(args) != len(sites) {
+		return nil, &ArgCountMismatchError{Query: query, Expected: len(sites), Actual: len(args)}
+	}
+	args = append([]any(nil), args...)
+	for i, site := range sites {
+		mismatch := &ArgTypeMismatchError{
+			Query:      query,
+			Index:      i,
+			Offset:     site.Offset,
+			FormatSpec: site.FormatSpec,
+			Value:      args[i],
+		}
+		switch {
+		case site.Section != nil:
+			section, ok := args[i].(SectionArg)
+			if !ok {
+				return nil, mismatch
+			}
+			if section.Enabled {
+				sectionArgs, err := checkArgs(query, site.Section.Sites, section.Args, strict)
+				if err != nil {
+					return nil, err
+				}
+				section.Args = sectionArgs
+			}
+			section.Format = site.Section.Format
+			args[i] = section
+		case site.Fragment:
+			if args[i] == nil {
+				return nil, &UnsetFragmentError{Query: query, Index: i}
+			}
+			if !isQueryValue(args[i]) {
+				mismatch.TypeName = site.TypeName
+				return nil, mismatch
+			}
+		case site.Mode == internal.ModeList:
+			list, ok := listFromSlice(args[i], site)
+			if !ok {
+				return nil, mismatch
+			}
+			elemTypeName, _ := strings.CutPrefix(site.TypeName, "[]")
+			for _, value := range list.Values {
+				if isQueryTypeName(elemTypeName) {
+					if value == nil {
+						return nil, &UnsetFragmentError{Query: query, Index: i}
+					}
+					if !isQueryValue(value) {
+						mismatch.Value = value
+						mismatch.TypeName = elemTypeName
+						return nil, mismatch
+					}
+				}
+				if !valueFitsFormatSpec(value, site.FormatSpec) {
+					mismatch.Value = value
+					return nil, mismatch
+				}
+				if strict && !valueHasType(value, elemTypeName) {
+					mismatch.Value = value
+					mismatch.TypeName = elemTypeName
+					return nil, mismatch
+				}
+			}
+			args[i] = list
+		case site.Mode == internal.ModeArray:
+			array, ok := arrayFromSlice(args[i])
+			if !ok {
+				return nil, mismatch
+			}
+			if arrayArg, ok := array.(ArrayArg); ok && strict && !valueHasType(arrayArg.Values, site.TypeName) {
+				mismatch.Value = arrayArg.Values
+				mismatch.TypeName = site.TypeName
+				return nil, mismatch
+			}
+			args[i] = array
+		default:
+			if !valueFitsFormatSpec(args[i], site.FormatSpec) {
+				return nil, mismatch
+			}
+			if strict && !valueHasType(args[i], site.TypeName) {
+				mismatch.TypeName = site.TypeName
+				return nil, mismatch
+			}
+		}
+	}
+	return args, nil
+}
+
+// MalformedInterpolationError is returned when the query text contains
+// something that looks like interpolation syntax, but is ill-formed.
+type MalformedInterpolationError struct {
+	Query string
+	// Offset is the byte offset of the ill-formed interpolation in Query.
+	Offset  int
+	Message string
+}
+
+var _ error = &MalformedInterpolationError{}
+
+func (e *MalformedInterpolationError) Error() string {
+	return fmt.Sprintf("malformed interpolation at offset %d: %s in query %s",
+		e.Offset, e.Message, abbreviate(e.Query))
+}
+
+// ArgCountMismatchError is returned when the number of arguments
+// returned by QueryVars.FormatArgs doesn't match what the query expects.
+type ArgCountMismatchError struct {
+	// Query is the query text or format string.
+	Query    string
+	Expected int
+	Actual   int
+}
+
+var _ error = &ArgCountMismatchError{}
+
+func (e *ArgCountMismatchError) Error() string {
+	return fmt.Sprintf("expected %d format args but got %d for query %s",
+		e.Expected, e.Actual, abbreviate(e.Query))
+}
+
+// ArgTypeMismatchError is returned when a value returned by
+// QueryVars.FormatArgs cannot be formatted using the format
+// specifier of the corresponding interpolation.
+type ArgTypeMismatchError struct {
+	Query string
+	// Index is the 0-based index of the interpolation.
+	Index int
+	// Offset is the byte offset of the interpolation in Query.
+	Offset     int
+	FormatSpec string
+	Value      any
+	// TypeName is set if Value doesn't have the type declared by the
+	// interpolation, which only DoStrict checks, except for fragments
+	// and lists of queries.
+	TypeName string
+}
+
+var _ error = &ArgTypeMismatchError{}
+
+func (e *ArgTypeMismatchError) Error() string {
+	if e.TypeName != "" {
+		return fmt.Sprintf("value of type %T for interpolation %d (at offset %d) doesn't have type %s in query %s",
+			e.Value, e.Index, e.Offset, e.TypeName, abbreviate(e.Query))
+	}
+	return fmt.Sprintf("value of type %T for interpolation %d (at offset %d) doesn't fit format specifier %s in query %s",
+		e.Value, e.Index, e.Offset, e.FormatSpec, abbreviate(e.Query))
+}
+
+// expandArgs replaces every ListArg in args with a *sqlf.Query joining
+// its elements, every Fragment with its query, and every SectionArg
+// with its contents or an empty query.
+func expandArgs(query string, args []any) ([]any, error) {
+	var expanded []any
+	for i, arg := range args {
+		var replacement *sqlf.Query
+		switch arg := arg.(type) {
+		case ListArg:
+			list, err := expandListElements(query, i, arg)
+			if err != nil {
+				return nil, err
+			}
+			replacement = list.query()
+			if replacement == nil {
+				return nil, &EmptyListError{Query: query, Index: i}
+			}
+		case SectionArg:
+			if !arg.Enabled {
+				replacement = sqlf.Sprintf("")
+				break
+			}
+			sectionArgs, err := expandArgs(query, arg.Args)
+			if err != nil {
+				return nil, err
+			}
+			replacement = sqlf.Sprintf(arg.Format, sectionArgs...)
+		case fragmentArg:
+			var err error
+			replacement, err = arg.fragmentQuery()
+			if err != nil {
+				return nil, err
+			}
+			if replacement == nil {
+				return nil, &UnsetFragmentError{Query: query, Index: i}
+			}
+		case *sqlf.Query:
+			if arg == nil {
+				return nil, &UnsetFragmentError{Query: query, Index: i}
+			}
+			continue
+		default:
+			continue
+		}
+		if expanded == nil {
+			// Don't modify the slice returned by FormatArgs.
+			expanded = append([]any(nil), args...)
+		}
+		expanded[i] = replacement
+	}
+	if expanded == nil {
+		return args, nil
+	}
+	return expanded, nil
+}
+
+func abbreviate(query string) string {
+	const maxLen = 40
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > maxLen {
+		query = query[:maxLen] + "..."
+	}
+	return strconv.Quote(query)
+}
+
+// DoFormat creates a sqlf.Query from a precomputed format string and QueryVars.
+//
+// Unlike Do, it doesn't need to parse the query text, so it is suitable
+// for hot paths. The format string and argument count are generated by
+// querygen next to each QueryVars type, e.g.
+//
+//	interpolate.DoFormat(myQueryVarsFormat, myQueryVarsArgCount, &myQueryVars{...})
+//
+// If the number of FormatArgs doesn't match argCount,
+// returns nil, &ArgCountMismatchError{}. If a ListArg is empty,
+// returns nil, &EmptyListError{}. If a fragment is unset,
+// returns nil, &UnsetFragmentError{}. If building the query for
+// a Fragment fails, returns the error from its Build.
+func DoFormat(format string, argCount int, q QueryVars) (*sqlf.Query, error) {
+	args := q.FormatArgs()
+	if len(args) != argCount {
+		return nil, &ArgCountMismatchError{Query: format, Expected: argCount, Actual: len(args)}
+	}
+	args, err := expandArgs(format, args)
+	if err != nil {
+		return nil, err
+	}
+	return sqlf.Sprintf(format, args...), nil
+}
+
+// MustDoFormat creates a sqlf.Query from a precomputed format string and QueryVars.
+//
+// Panics if DoFormat would return an error.
+func MustDoFormat(format string, argCount int, q QueryVars) *sqlf.Query {
+	result, err := DoFormat(format, argCount, q)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %25s", err.Error(), format))
+	}
+	return result
 }
 
 // MustDo creates a sqlf.Query from the given query string and QueryVars.
 //
-// Panics if the query doesn't use interpolation.
+// Panics if Do returns an error, i.e. if the query doesn't use interpolation,
+// or if the FormatArgs don't match the interpolations in the query.
 func MustDo(query string, q QueryVars) *sqlf.Query {
 	result, err := Do(query, q)
 	if err != nil {
@@ -45,3 +365,46 @@ func MustDo(query string, q QueryVars) *sqlf.Query {
 	}
 	return result
 }
+
+// MustDoStrict creates a sqlf.Query from the given query string and QueryVars.
+//
+// Panics if DoStrict returns an error, i.e. in the same cases as MustDo, or if
+// a value doesn't have the type declared by its interpolation.
+func MustDoStrict(query string, q QueryVars) *sqlf.Query {
+	result, err := DoStrict(query, q)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %25s", err.Error(), query))
+	}
+	return result
+}
+
+// expandListElements replaces Fragment elements of list with their
+// queries. Returns an UnsetFragmentError if any element is an unset
+// fragment, with the index of the list argument.
+func expandListElements(query string, index int, list ListArg) (ListArg, error) {
+	var expanded []any
+	for j, value := range list.Values {
+		switch value := value.(type) {
+		case fragmentArg:
+			fragment, err := value.fragmentQuery()
+			if err != nil {
+				return list, err
+			}
+			if fragment == nil {
+				return list, &UnsetFragmentError{Query: query, Index: index}
+			}
+			if expanded == nil {
+				expanded = append([]any(nil), list.Values...)
+			}
+			expanded[j] = fragment
+		case *sqlf.Query:
+			if value == nil {
+				return list, &UnsetFragmentError{Query: query, Index: index}
+			}
+		}
+	}
+	if expanded != nil {
+		list.Values = expanded
+	}
+	return list, nil
+}
@@ -9,20 +9,60 @@ type myArgsQueryVars struct {
 
 var _ QueryVars = &myArgsQueryVars{}
 
+const (
+	// myArgsQueryVarsFormat is the sqlf format string for myArgsQuery.
+	myArgsQueryVarsFormat = `SELECT * from %s WHERE id = %s`
+	// myArgsQueryVarsArgCount is the number of arguments for myArgsQueryVarsFormat.
+	myArgsQueryVarsArgCount = 2
+)
+
 func (qp *myArgsQueryVars) FormatArgs() []any {
 	return []any{qp.TableName, qp.WantId}
 }
 
+// Build creates a *sqlf.Query from myArgsQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *myArgsQueryVars) Build() (*Query, error) {
+	return DoFormat(myArgsQueryVarsFormat, myArgsQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *myArgsQueryVars) MustBuild() *Query {
+	return MustDoFormat(myArgsQueryVarsFormat, myArgsQueryVarsArgCount, qp)
+}
+
 type partyAttendeesQueryVars struct {
 	partyId int
 }
 
 var _ QueryVars = &partyAttendeesQueryVars{}
 
+const (
+	// partyAttendeesQueryVarsFormat is the sqlf format string for partyAttendeesQuery.
+	partyAttendeesQueryVarsFormat = `
+SELECT person_name
+FROM party_attendees
+WHERE party = %s
+`
+	// partyAttendeesQueryVarsArgCount is the number of arguments for partyAttendeesQueryVarsFormat.
+	partyAttendeesQueryVarsArgCount = 1
+)
+
 func (qp *partyAttendeesQueryVars) FormatArgs() []any {
 	return []any{qp.partyId}
 }
 
+// Build creates a *sqlf.Query from partyAttendeesQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *partyAttendeesQueryVars) Build() (*Query, error) {
+	return DoFormat(partyAttendeesQueryVarsFormat, partyAttendeesQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *partyAttendeesQueryVars) MustBuild() *Query {
+	return MustDoFormat(partyAttendeesQueryVarsFormat, partyAttendeesQueryVarsArgCount, qp)
+}
+
 type bestChoiceCakeQueryVars struct {
 	partyId          int
 	excludedCakeType string
@@ -30,6 +70,380 @@ type bestChoiceCakeQueryVars struct {
 
 var _ QueryVars = &bestChoiceCakeQueryVars{}
 
+const (
+	// bestChoiceCakeQueryVarsFormat is the sqlf format string for bestChoiceCakeQuery.
+	bestChoiceCakeQueryVarsFormat = `
+WITH attendees AS (
+SELECT person_name
+FROM party_attendees
+WHERE party = %s
+)
+
+SELECT fave_cakes.cake_type
+FROM attendees JOIN fave_cakes ON attendees.person_name = fave_cakes.person_name
+-- Need to allow host to exclude one cake they don't like
+WHERE fave_cake.cake_type != %s
+GROUP BY fave_cake.cake_type 
+ORDER BY COUNT(fave_cake.cake_type) DESC
+LIMIT 1
+`
+	// bestChoiceCakeQueryVarsArgCount is the number of arguments for bestChoiceCakeQueryVarsFormat.
+	bestChoiceCakeQueryVarsArgCount = 2
+)
+
 func (qp *bestChoiceCakeQueryVars) FormatArgs() []any {
 	return []any{qp.partyId, qp.excludedCakeType}
 }
+
+// Build creates a *sqlf.Query from bestChoiceCakeQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *bestChoiceCakeQueryVars) Build() (*Query, error) {
+	return DoFormat(bestChoiceCakeQueryVarsFormat, bestChoiceCakeQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *bestChoiceCakeQueryVars) MustBuild() *Query {
+	return MustDoFormat(bestChoiceCakeQueryVarsFormat, bestChoiceCakeQueryVarsArgCount, qp)
+}
+
+type cakeSearchQueryVars struct {
+	baker string
+}
+
+var _ QueryVars = &cakeSearchQueryVars{}
+
+const (
+	// cakeSearchQueryVarsFormat is the sqlf format string for cakeSearchQuery.
+	cakeSearchQueryVarsFormat = `SELECT name FROM cakes WHERE name LIKE 'choc%%' AND baker = %s`
+	// cakeSearchQueryVarsArgCount is the number of arguments for cakeSearchQueryVarsFormat.
+	cakeSearchQueryVarsArgCount = 1
+)
+
+func (qp *cakeSearchQueryVars) FormatArgs() []any {
+	return []any{qp.baker}
+}
+
+// Build creates a *sqlf.Query from cakeSearchQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *cakeSearchQueryVars) Build() (*Query, error) {
+	return DoFormat(cakeSearchQueryVarsFormat, cakeSearchQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *cakeSearchQueryVars) MustBuild() *Query {
+	return MustDoFormat(cakeSearchQueryVarsFormat, cakeSearchQueryVarsArgCount, qp)
+}
+
+type evenPartiesQueryVars struct {
+	since string
+}
+
+var _ QueryVars = &evenPartiesQueryVars{}
+
+const (
+	// evenPartiesQueryVarsFormat is the sqlf format string for evenPartiesQuery.
+	evenPartiesQueryVarsFormat = `SELECT id FROM parties WHERE id %% 2 = 0 AND date > %s`
+	// evenPartiesQueryVarsArgCount is the number of arguments for evenPartiesQueryVarsFormat.
+	evenPartiesQueryVarsArgCount = 1
+)
+
+func (qp *evenPartiesQueryVars) FormatArgs() []any {
+	return []any{qp.since}
+}
+
+// Build creates a *sqlf.Query from evenPartiesQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *evenPartiesQueryVars) Build() (*Query, error) {
+	return DoFormat(evenPartiesQueryVarsFormat, evenPartiesQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *evenPartiesQueryVars) MustBuild() *Query {
+	return MustDoFormat(evenPartiesQueryVarsFormat, evenPartiesQueryVarsArgCount, qp)
+}
+
+type attendanceQueryVars struct {
+	partyId int
+}
+
+var _ QueryVars = &attendanceQueryVars{}
+
+const (
+	// attendanceQueryVarsFormat is the sqlf format string for attendanceQuery.
+	attendanceQueryVarsFormat = `SELECT to_char(attended * 100 / invited, '999%%') FROM parties WHERE id = %d`
+	// attendanceQueryVarsArgCount is the number of arguments for attendanceQueryVarsFormat.
+	attendanceQueryVarsArgCount = 1
+)
+
+func (qp *attendanceQueryVars) FormatArgs() []any {
+	return []any{qp.partyId}
+}
+
+// Build creates a *sqlf.Query from attendanceQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *attendanceQueryVars) Build() (*Query, error) {
+	return DoFormat(attendanceQueryVarsFormat, attendanceQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *attendanceQueryVars) MustBuild() *Query {
+	return MustDoFormat(attendanceQueryVarsFormat, attendanceQueryVarsArgCount, qp)
+}
+
+type recentPartiesQueryVars struct {
+	limit *int
+}
+
+var _ QueryVars = &recentPartiesQueryVars{}
+
+const (
+	// recentPartiesQueryVarsFormat is the sqlf format string for recentPartiesQuery.
+	recentPartiesQueryVarsFormat = `SELECT id FROM parties ORDER BY date DESC LIMIT %d`
+	// recentPartiesQueryVarsArgCount is the number of arguments for recentPartiesQueryVarsFormat.
+	recentPartiesQueryVarsArgCount = 1
+)
+
+func (qp *recentPartiesQueryVars) FormatArgs() []any {
+	return []any{qp.limit}
+}
+
+// Build creates a *sqlf.Query from recentPartiesQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *recentPartiesQueryVars) Build() (*Query, error) {
+	return DoFormat(recentPartiesQueryVarsFormat, recentPartiesQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *recentPartiesQueryVars) MustBuild() *Query {
+	return MustDoFormat(recentPartiesQueryVarsFormat, recentPartiesQueryVarsArgCount, qp)
+}
+
+type quotedNameQueryVars struct {
+	name string
+}
+
+var _ QueryVars = &quotedNameQueryVars{}
+
+const (
+	// quotedNameQueryVarsFormat is the sqlf format string for quotedNameQuery.
+	quotedNameQueryVarsFormat = `SELECT id FROM "parties" WHERE name = %s`
+	// quotedNameQueryVarsArgCount is the number of arguments for quotedNameQueryVarsFormat.
+	quotedNameQueryVarsArgCount = 1
+)
+
+func (qp *quotedNameQueryVars) FormatArgs() []any {
+	return []any{qp.name}
+}
+
+// Build creates a *sqlf.Query from quotedNameQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *quotedNameQueryVars) Build() (*Query, error) {
+	return DoFormat(quotedNameQueryVarsFormat, quotedNameQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *quotedNameQueryVars) MustBuild() *Query {
+	return MustDoFormat(quotedNameQueryVarsFormat, quotedNameQueryVarsArgCount, qp)
+}
+
+type partiesByTagsQueryVars struct {
+	host string
+}
+
+var _ QueryVars = &partiesByTagsQueryVars{}
+
+const (
+	// partiesByTagsQueryVarsFormat is the sqlf format string for partiesByTagsQuery.
+	partiesByTagsQueryVarsFormat = `SELECT id FROM parties WHERE tags @> '{{a,b},{c,d}}'::text[][] -- not {{host}}
+AND host = %s`
+	// partiesByTagsQueryVarsArgCount is the number of arguments for partiesByTagsQueryVarsFormat.
+	partiesByTagsQueryVarsArgCount = 1
+)
+
+func (qp *partiesByTagsQueryVars) FormatArgs() []any {
+	return []any{qp.host}
+}
+
+// Build creates a *sqlf.Query from partiesByTagsQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *partiesByTagsQueryVars) Build() (*Query, error) {
+	return DoFormat(partiesByTagsQueryVarsFormat, partiesByTagsQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *partiesByTagsQueryVars) MustBuild() *Query {
+	return MustDoFormat(partiesByTagsQueryVarsFormat, partiesByTagsQueryVarsArgCount, qp)
+}
+
+type partyFilterQueryFragmentVars struct {
+	partyId int
+}
+
+var _ QueryVars = &partyFilterQueryFragmentVars{}
+
+const (
+	// partyFilterQueryFragmentVarsFormat is the sqlf format string for partyFilterQueryFragment.
+	partyFilterQueryFragmentVarsFormat = `party = %s`
+	// partyFilterQueryFragmentVarsArgCount is the number of arguments for partyFilterQueryFragmentVarsFormat.
+	partyFilterQueryFragmentVarsArgCount = 1
+)
+
+func (qp *partyFilterQueryFragmentVars) FormatArgs() []any {
+	return []any{qp.partyId}
+}
+
+// Build creates a *sqlf.Query from partyFilterQueryFragment using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *partyFilterQueryFragmentVars) Build() (*Query, error) {
+	return DoFormat(partyFilterQueryFragmentVarsFormat, partyFilterQueryFragmentVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *partyFilterQueryFragmentVars) MustBuild() *Query {
+	return MustDoFormat(partyFilterQueryFragmentVarsFormat, partyFilterQueryFragmentVarsArgCount, qp)
+}
+
+type filteredAttendeesQueryVars struct {
+	filter Fragment[*partyFilterQueryFragmentVars]
+	extra  *Query
+}
+
+var _ QueryVars = &filteredAttendeesQueryVars{}
+
+const (
+	// filteredAttendeesQueryVarsFormat is the sqlf format string for filteredAttendeesQuery.
+	filteredAttendeesQueryVarsFormat = `SELECT person_name FROM party_attendees WHERE %s AND %s`
+	// filteredAttendeesQueryVarsArgCount is the number of arguments for filteredAttendeesQueryVarsFormat.
+	filteredAttendeesQueryVarsArgCount = 2
+)
+
+func (qp *filteredAttendeesQueryVars) FormatArgs() []any {
+	return []any{qp.filter, qp.extra}
+}
+
+// Build creates a *sqlf.Query from filteredAttendeesQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *filteredAttendeesQueryVars) Build() (*Query, error) {
+	return DoFormat(filteredAttendeesQueryVarsFormat, filteredAttendeesQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *filteredAttendeesQueryVars) MustBuild() *Query {
+	return MustDoFormat(filteredAttendeesQueryVarsFormat, filteredAttendeesQueryVarsArgCount, qp)
+}
+
+type partiesByHostsQueryVars struct {
+	hosts []string
+}
+
+var _ QueryVars = &partiesByHostsQueryVars{}
+
+const (
+	// partiesByHostsQueryVarsFormat is the sqlf format string for partiesByHostsQuery.
+	partiesByHostsQueryVarsFormat = `SELECT id FROM parties WHERE host = ANY(%s)`
+	// partiesByHostsQueryVarsArgCount is the number of arguments for partiesByHostsQueryVarsFormat.
+	partiesByHostsQueryVarsArgCount = 1
+)
+
+func (qp *partiesByHostsQueryVars) FormatArgs() []any {
+	return []any{Array(qp.hosts)}
+}
+
+// Build creates a *sqlf.Query from partiesByHostsQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *partiesByHostsQueryVars) Build() (*Query, error) {
+	return DoFormat(partiesByHostsQueryVarsFormat, partiesByHostsQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *partiesByHostsQueryVars) MustBuild() *Query {
+	return MustDoFormat(partiesByHostsQueryVarsFormat, partiesByHostsQueryVarsArgCount, qp)
+}
+
+type partiesByIdQueryVars struct {
+	partyIds []int
+	host     string
+}
+
+var _ QueryVars = &partiesByIdQueryVars{}
+
+const (
+	// partiesByIdQueryVarsFormat is the sqlf format string for partiesByIdQuery.
+	partiesByIdQueryVarsFormat = `SELECT name FROM parties WHERE id IN (%s) AND host = %s`
+	// partiesByIdQueryVarsArgCount is the number of arguments for partiesByIdQueryVarsFormat.
+	partiesByIdQueryVarsArgCount = 2
+)
+
+func (qp *partiesByIdQueryVars) FormatArgs() []any {
+	return []any{List(qp.partyIds, "%d"), qp.host}
+}
+
+// Build creates a *sqlf.Query from partiesByIdQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *partiesByIdQueryVars) Build() (*Query, error) {
+	return DoFormat(partiesByIdQueryVarsFormat, partiesByIdQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *partiesByIdQueryVars) MustBuild() *Query {
+	return MustDoFormat(partiesByIdQueryVarsFormat, partiesByIdQueryVarsArgCount, qp)
+}
+
+type attendeesWhereQueryVars struct {
+	conds []*Query
+}
+
+var _ QueryVars = &attendeesWhereQueryVars{}
+
+const (
+	// attendeesWhereQueryVarsFormat is the sqlf format string for attendeesWhereQuery.
+	attendeesWhereQueryVarsFormat = `SELECT person_name FROM party_attendees WHERE %s`
+	// attendeesWhereQueryVarsArgCount is the number of arguments for attendeesWhereQueryVarsFormat.
+	attendeesWhereQueryVarsArgCount = 1
+)
+
+func (qp *attendeesWhereQueryVars) FormatArgs() []any {
+	return []any{List(qp.conds, "%s").WithSeparator(" AND ").OrEmpty("TRUE")}
+}
+
+// Build creates a *sqlf.Query from attendeesWhereQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *attendeesWhereQueryVars) Build() (*Query, error) {
+	return DoFormat(attendeesWhereQueryVarsFormat, attendeesWhereQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *attendeesWhereQueryVars) MustBuild() *Query {
+	return MustDoFormat(attendeesWhereQueryVarsFormat, attendeesWhereQueryVarsArgCount, qp)
+}
+
+type partiesQueryVars struct {
+	host             string
+	includeCancelled bool
+	byIds            bool
+	ids              []int
+}
+
+var _ QueryVars = &partiesQueryVars{}
+
+const (
+	// partiesQueryVarsFormat is the sqlf format string for partiesQuery.
+	partiesQueryVarsFormat = `SELECT id FROM parties WHERE host = %s%s%s`
+	// partiesQueryVarsArgCount is the number of arguments for partiesQueryVarsFormat.
+	partiesQueryVarsArgCount = 3
+)
+
+func (qp *partiesQueryVars) FormatArgs() []any {
+	return []any{qp.host, Section(!qp.includeCancelled, ` AND NOT cancelled`), Section(qp.byIds, ` AND id IN (%s)`, List(qp.ids, "%d"))}
+}
+
+// Build creates a *sqlf.Query from partiesQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *partiesQueryVars) Build() (*Query, error) {
+	return DoFormat(partiesQueryVarsFormat, partiesQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *partiesQueryVars) MustBuild() *Query {
+	return MustDoFormat(partiesQueryVarsFormat, partiesQueryVarsArgCount, qp)
+}
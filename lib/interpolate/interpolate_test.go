@@ -1,9 +1,12 @@
 package interpolate
 
 import (
+	"database/sql/driver"
 	"github.com/hexops/autogold/v2"
 	"github.com/keegancsmith/sqlf"
 	"github.com/stretchr/testify/require"
+	"math"
+	"strings"
 	"testing"
 )
 
@@ -29,6 +32,31 @@ LIMIT 1
 
 var _ = bestChoiceCakeQuery
 
+const cakeSearchQuery = `SELECT name FROM cakes WHERE name LIKE 'choc%' AND baker = {{baker : string}}`
+
+const evenPartiesQuery = `SELECT id FROM parties WHERE id % 2 = 0 AND date > {{since : string}}`
+
+const attendanceQuery = `SELECT to_char(attended * 100 / invited, '999%') FROM parties WHERE id = {{partyId : int : %d}}`
+
+const recentPartiesQuery = `SELECT id FROM parties ORDER BY date DESC LIMIT {{limit : *int : %d}}`
+
+const quotedNameQuery = "SELECT id FROM \"parties\" WHERE name = \x7b\x7bname : string}}"
+
+const partiesByTagsQuery = `SELECT id FROM parties WHERE tags @> '{{a,b},{c,d}}'::text[][] -- not \{{host}}
+AND host = {{host : string}}`
+
+const partyFilterQueryFragment = `party = {{partyId : int}}`
+
+const filteredAttendeesQuery = `SELECT person_name FROM party_attendees WHERE {{filter : fragment(partyFilterQueryFragment)}} AND {{extra : fragment}}`
+
+const partiesByHostsQuery = `SELECT id FROM parties WHERE host = ANY({{hosts : []string : array}})`
+
+const partiesByIdQuery = `SELECT name FROM parties WHERE id IN ({{partyIds : []int : list : %d}}) AND host = {{host : string}}`
+
+const attendeesWhereQuery = `SELECT person_name FROM party_attendees WHERE {{conds : []*Query : join " AND " : empty "TRUE"}}`
+
+const partiesQuery = `SELECT id FROM parties WHERE host = {{host : string}}{{#if !includeCancelled}} AND NOT cancelled{{/if}}{{#if byIds}} AND id IN ({{ids : []int : list : %d}}){{/if}}`
+
 func TestDo(t *testing.T) {
 	type TestCase struct {
 		query      string
@@ -44,6 +72,90 @@ func TestDo(t *testing.T) {
 			expect:     autogold.Expect("SELECT * from $1 WHERE id = $2"),
 			expectArgs: autogold.Expect([]interface{}{"T", 1}),
 		},
+		{
+			query:      recentPartiesQuery,
+			input:      &recentPartiesQueryVars{limit: new(int)},
+			expect:     autogold.Expect("SELECT id FROM parties ORDER BY date DESC LIMIT $1"),
+			expectArgs: autogold.Expect([]interface{}{new(int)}),
+		},
+		{
+			query:      cakeSearchQuery,
+			input:      &cakeSearchQueryVars{baker: "Bob"},
+			expect:     autogold.Expect("SELECT name FROM cakes WHERE name LIKE 'choc%' AND baker = $1"),
+			expectArgs: autogold.Expect([]interface{}{"Bob"}),
+		},
+		{
+			query:      evenPartiesQuery,
+			input:      &evenPartiesQueryVars{since: "2024-01-01"},
+			expect:     autogold.Expect("SELECT id FROM parties WHERE id % 2 = 0 AND date > $1"),
+			expectArgs: autogold.Expect([]interface{}{"2024-01-01"}),
+		},
+		{
+			query:      attendanceQuery,
+			input:      &attendanceQueryVars{partyId: 7},
+			expect:     autogold.Expect("SELECT to_char(attended * 100 / invited, '999%') FROM parties WHERE id = $1"),
+			expectArgs: autogold.Expect([]interface{}{7}),
+		},
+		{
+			query:      quotedNameQuery,
+			input:      &quotedNameQueryVars{name: "Bob's"},
+			expect:     autogold.Expect(`SELECT id FROM "parties" WHERE name = $1`),
+			expectArgs: autogold.Expect([]interface{}{"Bob's"}),
+		},
+		{
+			query:      partiesByTagsQuery,
+			input:      &partiesByTagsQueryVars{host: "Bob"},
+			expect:     autogold.Expect("SELECT id FROM parties WHERE tags @> '{{a,b},{c,d}}'::text[][] -- not {{host}}\nAND host = $1"),
+			expectArgs: autogold.Expect([]interface{}{"Bob"}),
+		},
+		{
+			query:      partiesByIdQuery,
+			input:      &partiesByIdQueryVars{partyIds: []int{1, 2, 3}, host: "Bob"},
+			expect:     autogold.Expect("SELECT name FROM parties WHERE id IN ($1, $2, $3) AND host = $4"),
+			expectArgs: autogold.Expect([]interface{}{1, 2, 3, "Bob"}),
+		},
+		{
+			query:      partiesByHostsQuery,
+			input:      &partiesByHostsQueryVars{hosts: []string{"Bob", "Alice"}},
+			expect:     autogold.Expect("SELECT id FROM parties WHERE host = ANY($1)"),
+			expectArgs: autogold.Expect([]interface{}{ArrayArg{Values: []string{"Bob", "Alice"}}}),
+		},
+		{
+			query: filteredAttendeesQuery,
+			input: &filteredAttendeesQueryVars{
+				filter: NewFragment(&partyFilterQueryFragmentVars{partyId: 3}),
+				extra:  sqlf.Sprintf("person_name <> %s", "Bob"),
+			},
+			expect:     autogold.Expect("SELECT person_name FROM party_attendees WHERE party = $1 AND person_name <> $2"),
+			expectArgs: autogold.Expect([]interface{}{3, "Bob"}),
+		},
+		{
+			query: attendeesWhereQuery,
+			input: &attendeesWhereQueryVars{conds: []*Query{
+				sqlf.Sprintf("party = %s", 3),
+				sqlf.Sprintf("person_name LIKE 'B%%'"),
+			}},
+			expect:     autogold.Expect("SELECT person_name FROM party_attendees WHERE party = $1 AND person_name LIKE 'B%'"),
+			expectArgs: autogold.Expect([]interface{}{3}),
+		},
+		{
+			query:      attendeesWhereQuery,
+			input:      &attendeesWhereQueryVars{},
+			expect:     autogold.Expect("SELECT person_name FROM party_attendees WHERE TRUE"),
+			expectArgs: autogold.Expect([]interface{}{}),
+		},
+		{
+			query:      partiesQuery,
+			input:      &partiesQueryVars{host: "Bob"},
+			expect:     autogold.Expect("SELECT id FROM parties WHERE host = $1 AND NOT cancelled"),
+			expectArgs: autogold.Expect([]interface{}{"Bob"}),
+		},
+		{
+			query:      partiesQuery,
+			input:      &partiesQueryVars{host: "Bob", includeCancelled: true, byIds: true, ids: []int{1, 2}},
+			expect:     autogold.Expect("SELECT id FROM parties WHERE host = $1 AND id IN ($2, $3)"),
+			expectArgs: autogold.Expect([]interface{}{"Bob", 1, 2}),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -51,11 +163,325 @@ func TestDo(t *testing.T) {
 		require.NoError(t, err)
 		tc.expect.Equal(t, query.Query(sqlf.PostgresBindVar))
 		tc.expectArgs.Equal(t, query.Args())
+
+		// Generated vars always have the declared types.
+		strictQuery, err := DoStrict(tc.query, tc.input)
+		require.NoError(t, err)
+		require.Equal(t, query, strictQuery)
+
+		// Generated code must escape percent signs the same way
+		built, err := tc.input.(interface{ Build() (*Query, error) }).Build()
+		require.NoError(t, err)
+		require.Equal(t, query.Query(sqlf.PostgresBindVar), built.Query(sqlf.PostgresBindVar))
+	}
+}
+
+func TestDoFormat(t *testing.T) {
+	vars := &bestChoiceCakeQueryVars{partyId: 3, excludedCakeType: "fruit"}
+	expected, err := Do(bestChoiceCakeQuery, vars)
+	require.NoError(t, err)
+
+	query, err := DoFormat(bestChoiceCakeQueryVarsFormat, bestChoiceCakeQueryVarsArgCount, vars)
+	require.NoError(t, err)
+	require.Equal(t, expected.Query(sqlf.PostgresBindVar), query.Query(sqlf.PostgresBindVar))
+	require.Equal(t, expected.Args(), query.Args())
+
+	_, err = DoFormat(partyAttendeesQueryVarsFormat, partyAttendeesQueryVarsArgCount, vars)
+	require.ErrorAs(t, err, new(*ArgCountMismatchError))
+}
+
+func TestBuild(t *testing.T) {
+	vars := &bestChoiceCakeQueryVars{partyId: 3, excludedCakeType: "fruit"}
+	expected := MustDo(bestChoiceCakeQuery, vars)
+	query, err := vars.Build()
+	require.NoError(t, err)
+	require.Equal(t, expected.Query(sqlf.PostgresBindVar), query.Query(sqlf.PostgresBindVar))
+	require.Equal(t, expected.Args(), query.Args())
+	require.Equal(t, query, vars.MustBuild())
+}
+
+func BenchmarkDo(b *testing.B) {
+	vars := &bestChoiceCakeQueryVars{partyId: 3, excludedCakeType: "fruit"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = MustDo(bestChoiceCakeQuery, vars)
+	}
+}
+
+func BenchmarkDoFormat(b *testing.B) {
+	vars := &bestChoiceCakeQueryVars{partyId: 3, excludedCakeType: "fruit"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = MustDoFormat(bestChoiceCakeQueryVarsFormat, bestChoiceCakeQueryVarsArgCount, vars)
 	}
 }
 
+type handWrittenVars []any
+
+func (h handWrittenVars) FormatArgs() []any {
+	return h
+}
+
+func TestDoValidation(t *testing.T) {
+	_, err := Do(recentPartiesQuery, handWrittenVars{3})
+	require.NoError(t, err)
+	_, err = Do(recentPartiesQuery, handWrittenVars{nil})
+	require.NoError(t, err)
+
+	_, err = Do(myArgsQuery, handWrittenVars{"T"})
+	var countErr *ArgCountMismatchError
+	require.ErrorAs(t, err, &countErr)
+	autogold.Expect(`expected 2 format args but got 1 for query "SELECT * from {{TableName: string}} WHER..."`).Equal(t, countErr.Error())
+
+	_, err = Do(recentPartiesQuery, handWrittenVars{"foobar"})
+	var typeErr *ArgTypeMismatchError
+	require.ErrorAs(t, err, &typeErr)
+	require.Equal(t, 0, typeErr.Index)
+	require.Equal(t, len("SELECT id FROM parties ORDER BY date DESC LIMIT "), typeErr.Offset)
+
+	require.Panics(t, func() {
+		MustDo(recentPartiesQuery, handWrittenVars{"foobar"})
+	})
+
+	_, err = Do(partiesByIdQuery, handWrittenVars{[]int64{1, 2}, "Bob"})
+	require.NoError(t, err)
+	_, err = Do(partiesByIdQuery, handWrittenVars{1, "Bob"})
+	require.ErrorAs(t, err, &typeErr)
+	_, err = Do(partiesByIdQuery, handWrittenVars{[]string{"1"}, "Bob"})
+	require.ErrorAs(t, err, &typeErr)
+	require.Equal(t, "1", typeErr.Value)
+
+	_, err = Do(partiesByHostsQuery, handWrittenVars{[]string{"Bob"}})
+	require.NoError(t, err)
+	_, err = Do(partiesByHostsQuery, handWrittenVars{"Bob"})
+	require.ErrorAs(t, err, &typeErr)
+
+	_, err = Do("SELECT * FROM t WHERE id = {{id int}}", handWrittenVars{1})
+	var malformedErr *MalformedInterpolationError
+	require.ErrorAs(t, err, &malformedErr)
+	require.Equal(t, len("SELECT * FROM t WHERE id = "), malformedErr.Offset)
+}
+
+func TestDoStrict(t *testing.T) {
+	limit := 3
+	testCases := []struct {
+		query    string
+		args     handWrittenVars
+		index    int
+		typeName string
+	}{
+		// Do accepts any integer for %d, and anything for %s.
+		{query: myArgsQuery, args: handWrittenVars{"T", int64(1)}, index: 1, typeName: "int"},
+		{query: myArgsQuery, args: handWrittenVars{3, 1}, index: 0, typeName: "string"},
+		{query: myArgsQuery, args: handWrittenVars{nil, 1}, index: 0, typeName: "string"},
+		// Do dereferences pointers, and accepts non-pointers.
+		{query: recentPartiesQuery, args: handWrittenVars{&limit}, index: -1},
+		{query: recentPartiesQuery, args: handWrittenVars{nil}, index: -1},
+		{query: recentPartiesQuery, args: handWrittenVars{limit}, index: 0, typeName: "*int"},
+		// Elements of lists are checked against the element type.
+		{query: partiesByIdQuery, args: handWrittenVars{[]int{1}, "Bob"}, index: -1},
+		{query: partiesByIdQuery, args: handWrittenVars{[]int64{1, 2}, "Bob"}, index: 0, typeName: "int"},
+		{query: partiesByHostsQuery, args: handWrittenVars{[]any{"Bob"}}, index: 0, typeName: "[]string"},
+		// Types which need a package to be named are not checked.
+		{query: attendeesWhereQuery, args: handWrittenVars{[]any{sqlf.Sprintf("TRUE")}}, index: -1},
+	}
+	for _, tc := range testCases {
+		_, err := Do(tc.query, tc.args)
+		require.NoError(t, err)
+
+		_, err = DoStrict(tc.query, tc.args)
+		if tc.index < 0 {
+			require.NoError(t, err)
+			continue
+		}
+		var typeErr *ArgTypeMismatchError
+		require.ErrorAs(t, err, &typeErr, "%v", tc.args)
+		require.Equal(t, tc.index, typeErr.Index)
+		require.Equal(t, tc.typeName, typeErr.TypeName)
+	}
+
+	_, err := DoStrict(myArgsQuery, handWrittenVars{"T", int64(1)})
+	autogold.Expect(`value of type int64 for interpolation 1 (at offset 47) doesn't have type int in query "SELECT * from {{TableName: string}} WHER..."`).Equal(t, err.Error())
+
+	require.NotPanics(t, func() {
+		MustDo(myArgsQuery, handWrittenVars{"T", int64(1)})
+	})
+	require.Panics(t, func() {
+		MustDoStrict(myArgsQuery, handWrittenVars{"T", int64(1)})
+	})
+}
+
+func TestEmptyList(t *testing.T) {
+	vars := &partiesByIdQueryVars{host: "Bob"}
+	_, err := Do(partiesByIdQuery, vars)
+	var emptyErr *EmptyListError
+	require.ErrorAs(t, err, &emptyErr)
+	require.Equal(t, 0, emptyErr.Index)
+	autogold.Expect(`empty list for interpolation 0 in query "SELECT name FROM parties WHERE id IN ({{..."; handle the empty case before building the query or use the empty option`).Equal(t, emptyErr.Error())
+
+	_, err = DoFormat(partiesByIdQueryVarsFormat, partiesByIdQueryVarsArgCount, vars)
+	require.ErrorAs(t, err, &emptyErr)
+	_, err = vars.Build()
+	require.ErrorAs(t, err, &emptyErr)
+	require.Panics(t, func() {
+		vars.MustBuild()
+	})
+}
+
+func TestUnsetFragment(t *testing.T) {
+	vars := &filteredAttendeesQueryVars{extra: sqlf.Sprintf("TRUE")}
+	_, err := Do(filteredAttendeesQuery, vars)
+	var unsetErr *UnsetFragmentError
+	require.ErrorAs(t, err, &unsetErr)
+	require.Equal(t, 0, unsetErr.Index)
+
+	vars = &filteredAttendeesQueryVars{filter: NewFragment(&partyFilterQueryFragmentVars{partyId: 3})}
+	_, err = DoFormat(filteredAttendeesQueryVarsFormat, filteredAttendeesQueryVarsArgCount, vars)
+	require.ErrorAs(t, err, &unsetErr)
+	require.Equal(t, 1, unsetErr.Index)
+	_, err = vars.Build()
+	require.ErrorAs(t, err, &unsetErr)
+
+	// Errors building a fragment are returned when building the query using it.
+	vars = &filteredAttendeesQueryVars{
+		filter: NewFragment(&partyFilterQueryFragmentVars{partyId: 3}),
+		extra:  sqlf.Sprintf("TRUE"),
+	}
+	_, err = vars.Build()
+	require.NoError(t, err)
+	_, err = Do(attendeesWhereQuery, handWrittenVars{[]any{NewFragment(&partiesByIdQueryVars{host: "Bob"})}})
+	var emptyErr *EmptyListError
+	require.ErrorAs(t, err, &emptyErr)
+	require.Equal(t, partiesByIdQueryVarsFormat, emptyErr.Query)
+}
+
+func TestFragmentValidation(t *testing.T) {
+	filter := NewFragment(&partyFilterQueryFragmentVars{partyId: 3})
+	_, err := Do(filteredAttendeesQuery, handWrittenVars{filter, sqlf.Sprintf("TRUE")})
+	require.NoError(t, err)
+
+	// Fragments only accept queries.
+	_, err = Do(filteredAttendeesQuery, handWrittenVars{filter, "TRUE"})
+	var typeErr *ArgTypeMismatchError
+	require.ErrorAs(t, err, &typeErr)
+	require.Equal(t, 1, typeErr.Index)
+	require.Equal(t, "fragment", typeErr.TypeName)
+	_, err = Do(filteredAttendeesQuery, handWrittenVars{3, sqlf.Sprintf("TRUE")})
+	require.ErrorAs(t, err, &typeErr)
+	require.Equal(t, "fragment(partyFilterQueryFragment)", typeErr.TypeName)
+	_, err = Do(attendeesWhereQuery, handWrittenVars{[]any{sqlf.Sprintf("TRUE"), "FALSE"}})
+	require.ErrorAs(t, err, &typeErr)
+	require.Equal(t, "FALSE", typeErr.Value)
+	require.Equal(t, "*Query", typeErr.TypeName)
+
+	// Untyped nils are unset fragments.
+	_, err = Do(filteredAttendeesQuery, handWrittenVars{filter, nil})
+	var unsetErr *UnsetFragmentError
+	require.ErrorAs(t, err, &unsetErr)
+	require.Equal(t, 1, unsetErr.Index)
+	_, err = Do(attendeesWhereQuery, handWrittenVars{[]any{nil}})
+	require.ErrorAs(t, err, &unsetErr)
+}
+
+func TestJoin(t *testing.T) {
+	// Hand-written FormatArgs get the separator and empty text from the query.
+	query, err := Do(attendeesWhereQuery, handWrittenVars{[]any{
+		NewFragment(&partyFilterQueryFragmentVars{partyId: 3}),
+		sqlf.Sprintf("person_name <> %s", "Bob"),
+	}})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT person_name FROM party_attendees WHERE party = $1 AND person_name <> $2",
+		query.Query(sqlf.PostgresBindVar))
+	query, err = Do(attendeesWhereQuery, handWrittenVars{[]*Query{}})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT person_name FROM party_attendees WHERE TRUE", query.Query(sqlf.PostgresBindVar))
+
+	vars := &attendeesWhereQueryVars{conds: []*Query{sqlf.Sprintf("TRUE"), nil}}
+	_, err = Do(attendeesWhereQuery, vars)
+	var unsetErr *UnsetFragmentError
+	require.ErrorAs(t, err, &unsetErr)
+	_, err = vars.Build()
+	require.ErrorAs(t, err, &unsetErr)
+}
+
+func TestHandBuiltList(t *testing.T) {
+	// Options which aren't set are taken from the query.
+	query, err := Do(partiesByIdQuery, handWrittenVars{ListArg{Values: []any{1, 2}}, "Bob"})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT name FROM parties WHERE id IN ($1, $2) AND host = $3", query.Query(sqlf.PostgresBindVar))
+	require.Equal(t, []any{1, 2, "Bob"}, query.Args())
+	query, err = Do(attendeesWhereQuery, handWrittenVars{ListArg{}})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT person_name FROM party_attendees WHERE TRUE", query.Query(sqlf.PostgresBindVar))
+
+	// DoFormat uses the defaults.
+	query, err = DoFormat(partiesByIdQueryVarsFormat, partiesByIdQueryVarsArgCount,
+		handWrittenVars{ListArg{Values: []any{1, 2}}, "Bob"})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT name FROM parties WHERE id IN ($1, $2) AND host = $3", query.Query(sqlf.PostgresBindVar))
+}
+
+func TestSections(t *testing.T) {
+	// Do uses the contents of sections from the query text.
+	query, err := Do(partiesQuery, handWrittenVars{"Bob", Section(true, ""), Section(true, "", []int{3})})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT id FROM parties WHERE host = $1 AND NOT cancelled AND id IN ($2)",
+		query.Query(sqlf.PostgresBindVar))
+
+	var typeErr *ArgTypeMismatchError
+	_, err = Do(partiesQuery, handWrittenVars{"Bob", true, Section(false, "")})
+	require.ErrorAs(t, err, &typeErr)
+	require.Equal(t, 1, typeErr.Index)
+	_, err = Do(partiesQuery, handWrittenVars{"Bob", Section(false, ""), Section(true, "", []string{"3"})})
+	require.ErrorAs(t, err, &typeErr)
+	require.Equal(t, 0, typeErr.Index)
+	require.Equal(t, strings.Index(partiesQuery, "{{ids"), typeErr.Offset)
+	var countErr *ArgCountMismatchError
+	_, err = Do(partiesQuery, handWrittenVars{"Bob", Section(false, ""), Section(true, "")})
+	require.ErrorAs(t, err, &countErr)
+
+	// Lists are only expanded in enabled sections.
+	vars := &partiesQueryVars{host: "Bob", byIds: true}
+	_, err = Do(partiesQuery, vars)
+	var emptyErr *EmptyListError
+	require.ErrorAs(t, err, &emptyErr)
+	_, err = vars.Build()
+	require.ErrorAs(t, err, &emptyErr)
+}
+
+func TestArrayArg(t *testing.T) {
+	one := 1
+	testCases := []struct {
+		values any
+		want   driver.Value
+	}{
+		{[]int(nil), nil},
+		{[]int{}, "{}"},
+		{[]int64{1, -2}, "{1,-2}"},
+		{[]uint8{1, 2}, "{1,2}"},
+		{[]bool{true, false}, "{t,f}"},
+		{[]float64{1.5, math.Inf(1), math.NaN()}, "{1.5,Infinity,NaN}"},
+		{[]string{"a", "", `q"b\`, "NULL", "a b"}, `{"a","","q\"b\\","NULL","a b"}`},
+		{[]*int{nil, &one}, "{NULL,1}"},
+		{[][]byte{{0xde, 0xad}, nil}, `{"\\xdead",NULL}`},
+	}
+	for _, testCase := range testCases {
+		got, err := ArrayArg{Values: testCase.values}.Value()
+		require.NoError(t, err)
+		require.Equal(t, testCase.want, got, "%#v", testCase.values)
+	}
+
+	got, err := Array([]any{"a", 1, nil}).Value()
+	require.NoError(t, err)
+	require.Equal(t, `{"a",1,NULL}`, got)
+
+	_, err = ArrayArg{Values: []struct{}{{}}}.Value()
+	require.Error(t, err)
+}
+
 func TestSqlf(t *testing.T) {
-	// This seems weird, should we do our own run-time type-checking?
+	// sqlf doesn't type-check arguments against verbs,
+	// which is why Do validates arguments itself.
 	require.NotPanics(t, func() {
 		query := sqlf.Sprintf("%d", "foobar")
 		val := autogold.Expect("$1")
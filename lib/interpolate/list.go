@@ -0,0 +1,124 @@
+package interpolate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/querygen/internal"
+)
+
+// ListArg is the argument for a {{x : []T : list}} or
+// {{x : []T : join " AND "}} interpolation.
+//
+// It is expanded by Do and DoFormat into a list with one bind variable
+// per element, e.g. for IN (...) or VALUES (...). Elements which are
+// queries, e.g. conditions for a WHERE clause, are spliced in instead.
+//
+// Do fills in the options which aren't set, e.g. for a hand-built
+// ListArg{Values: values}, from the interpolation.
+type ListArg struct {
+	Values []any
+	// FormatSpec is the format specifier for each element,
+	// %s by default.
+	FormatSpec string
+	// Separator is the text between elements, ", " by default.
+	Separator string
+	// Empty is the text used in place of an empty list. If nil,
+	// an empty list is an error.
+	Empty *string
+}
+
+// List creates a ListArg from values. Generated FormatArgs methods
+// call it for interpolations using the list mode.
+func List[T any](values []T, formatSpec string) ListArg {
+	anyValues := make([]any, len(values))
+	for i, value := range values {
+		anyValues[i] = value
+	}
+	return ListArg{Values: anyValues, FormatSpec: formatSpec, Separator: internal.DefaultListSeparator}
+}
+
+// WithSeparator returns a copy of l using separator between elements.
+func (l ListArg) WithSeparator(separator string) ListArg {
+	l.Separator = separator
+	return l
+}
+
+// OrEmpty returns a copy of l which expands to text, e.g. TRUE,
+// if there are no elements.
+func (l ListArg) OrEmpty(text string) ListArg {
+	l.Empty = &text
+	return l
+}
+
+// listFromSlice creates a ListArg from a slice or array of any type,
+// for FormatArgs which weren't generated by querygen. The options
+// of a ListArg which aren't set are taken from site.
+func listFromSlice(value any, site internal.InterpolationSite) (ListArg, bool) {
+	if list, ok := value.(ListArg); ok {
+		if list.FormatSpec == "" {
+			list.FormatSpec = site.FormatSpec
+		}
+		if list.Separator == "" {
+			list.Separator = site.Separator
+		}
+		if list.Empty == nil {
+			list.Empty = site.Empty
+		}
+		return list, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return ListArg{}, false
+	}
+	values := make([]any, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return ListArg{Values: values, FormatSpec: site.FormatSpec, Separator: site.Separator, Empty: site.Empty}, true
+}
+
+// query joins the elements of l, returning nil if l is empty and
+// doesn't have an Empty text.
+func (l ListArg) query() *sqlf.Query {
+	if len(l.Values) == 0 {
+		if l.Empty == nil {
+			return nil
+		}
+		return sqlf.Sprintf(escapePercents(*l.Empty))
+	}
+	formatSpec := l.FormatSpec
+	if formatSpec == "" {
+		formatSpec = internal.DefaultFormatSpec
+	}
+	separator := l.Separator
+	if separator == "" {
+		separator = internal.DefaultListSeparator
+	}
+	format := strings.Repeat(formatSpec+escapePercents(separator), len(l.Values)-1) + formatSpec
+	return sqlf.Sprintf(format, l.Values...)
+}
+
+func escapePercents(text string) string {
+	return strings.ReplaceAll(text, "%", "%%")
+}
+
+// EmptyListError is returned when the value for a list interpolation
+// has no elements, since that would produce invalid SQL like IN ().
+type EmptyListError struct {
+	// Query is the query text or format string.
+	Query string
+	// Index is the 0-based index of the interpolation.
+	Index int
+}
+
+var _ error = &EmptyListError{}
+
+func (e *EmptyListError) Error() string {
+	return fmt.Sprintf("empty list for interpolation %d in query %s; "+
+		"handle the empty case before building the query or use the empty option",
+		e.Index, abbreviate(e.Query))
+}
@@ -0,0 +1,24 @@
+package interpolate
+
+// SectionArg is the argument for a conditional section like
+// {{#if cond}} ... {{/if}}.
+//
+// It is expanded by Do and DoFormat into the contents of the section
+// if Enabled, and into nothing otherwise, in which case Args are ignored.
+// Errors for the arguments of a section, such as an EmptyListError,
+// have the Index of the argument within Args.
+type SectionArg struct {
+	Enabled bool
+	// Format is the format string for the contents of the section.
+	// Do uses the contents from the query text instead.
+	Format string
+	// Args has 1 element per interpolation or nested section
+	// in the contents of the section.
+	Args []any
+}
+
+// Section creates a SectionArg. Generated FormatArgs methods call it
+// for conditional sections, with enabled set from the section's field.
+func Section(enabled bool, format string, args ...any) SectionArg {
+	return SectionArg{Enabled: enabled, Format: format, Args: args}
+}
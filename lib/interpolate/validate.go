@@ -0,0 +1,140 @@
+package interpolate
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+
+	"github.com/keegancsmith/sqlf"
+)
+
+// valueFitsFormatSpec checks if value can be sensibly formatted using formatSpec.
+//
+// sqlf itself ignores the verb and always emits a bind variable,
+// so this catches mistakes like passing a string for %d.
+func valueFitsFormatSpec(value any, formatSpec string) bool {
+	verb := formatSpec[len(formatSpec)-1]
+	switch value.(type) {
+	case nil:
+		return true
+	case *sqlf.Query:
+		// Nested queries are spliced in as-is.
+		return verb == 's' || verb == 'v'
+	case driver.Valuer:
+		// The driver decides how to encode the value.
+		return true
+	}
+
+	kind := reflect.TypeOf(value).Kind()
+	for kind == reflect.Pointer {
+		rv := reflect.ValueOf(value)
+		if rv.IsNil() {
+			return true
+		}
+		value = rv.Elem().Interface()
+		kind = reflect.TypeOf(value).Kind()
+	}
+
+	switch verb {
+	case 'd':
+		return isIntegerKind(kind)
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		return kind == reflect.Float32 || kind == reflect.Float64
+	case 't':
+		return kind == reflect.Bool
+	case 'q':
+		return kind == reflect.String || isIntegerKind(kind)
+	default:
+		return true
+	}
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+// isQueryValue checks if value can be spliced in for a fragment,
+// i.e. it is a *Query or a Fragment.
+func isQueryValue(value any) bool {
+	switch value.(type) {
+	case *sqlf.Query, fragmentArg:
+		return true
+	default:
+		return false
+	}
+}
+
+// isQueryTypeName checks if typeName is *Query or a Fragment, as used
+// for the elements of lists like {{conds : []*sqlf.Query : join " AND "}}.
+func isQueryTypeName(typeName string) bool {
+	switch typeName {
+	case "*Query", "*sqlf.Query", "*interpolate.Query":
+		return true
+	default:
+		return strings.HasPrefix(typeName, "Fragment[") || strings.HasPrefix(typeName, "interpolate.Fragment[")
+	}
+}
+
+// valueHasType checks if value has the type named typeName.
+//
+// Only predeclared types, and pointers to and slices of them, are checked,
+// since other types can't be resolved from their name at run-time.
+func valueHasType(value any, typeName string) bool {
+	typ, ok := predeclaredType(typeName)
+	if !ok {
+		return true
+	}
+	if value == nil {
+		return typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice
+	}
+	return reflect.TypeOf(value) == typ
+}
+
+var predeclaredTypes = map[string]reflect.Type{
+	"bool":       reflect.TypeFor[bool](),
+	"string":     reflect.TypeFor[string](),
+	"int":        reflect.TypeFor[int](),
+	"int8":       reflect.TypeFor[int8](),
+	"int16":      reflect.TypeFor[int16](),
+	"int32":      reflect.TypeFor[int32](),
+	"rune":       reflect.TypeFor[rune](),
+	"int64":      reflect.TypeFor[int64](),
+	"uint":       reflect.TypeFor[uint](),
+	"uint8":      reflect.TypeFor[uint8](),
+	"byte":       reflect.TypeFor[byte](),
+	"uint16":     reflect.TypeFor[uint16](),
+	"uint32":     reflect.TypeFor[uint32](),
+	"uint64":     reflect.TypeFor[uint64](),
+	"uintptr":    reflect.TypeFor[uintptr](),
+	"float32":    reflect.TypeFor[float32](),
+	"float64":    reflect.TypeFor[float64](),
+	"complex64":  reflect.TypeFor[complex64](),
+	"complex128": reflect.TypeFor[complex128](),
+}
+
+// predeclaredType returns the type named typeName, if it is a predeclared
+// type, or a pointer to or slice of one.
+func predeclaredType(typeName string) (reflect.Type, bool) {
+	if elem, ok := strings.CutPrefix(typeName, "*"); ok {
+		typ, ok := predeclaredType(elem)
+		if !ok {
+			return nil, false
+		}
+		return reflect.PointerTo(typ), true
+	}
+	if elem, ok := strings.CutPrefix(typeName, "[]"); ok {
+		typ, ok := predeclaredType(elem)
+		if !ok {
+			return nil, false
+		}
+		return reflect.SliceOf(typ), true
+	}
+	typ, ok := predeclaredTypes[typeName]
+	return typ, ok
+}
@@ -0,0 +1,93 @@
+package querygen
+
+import (
+	"encoding/json"
+	"flag"
+	"go/build"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/sourcegraph/querygen/internal"
+)
+
+var rootPackages struct {
+	once sync.Once
+	// paths is nil if all analyzed packages are roots.
+	paths internal.Set[string]
+}
+
+// isRootPackage reports whether pkg is one of the packages querygen was
+// asked to process, as opposed to a dependency which is only analyzed to
+// compute facts. Generated files are only written for root packages.
+//
+// The root packages are determined once per process from the command line,
+// so this must only be called when running as the querygen CLI or as
+// a vet tool, where the arguments are package patterns or a vet config.
+func isRootPackage(logger *log.Logger, pkg *types.Package) bool {
+	rootPackages.once.Do(func() {
+		paths, err := loadRootPackagePaths(flag.Args())
+		if err != nil {
+			logger.Warn("failed to determine root packages", "err", err)
+			return
+		}
+		rootPackages.paths = paths
+	})
+	if rootPackages.paths == nil {
+		return true
+	}
+	// External test packages are named after the package under test.
+	return rootPackages.paths.Has(strings.TrimSuffix(pkg.Path(), "_test"))
+}
+
+// isStandardLibrary reports whether pass is for a package in GOROOT.
+func isStandardLibrary(pass *analysis.Pass) bool {
+	goroot := build.Default.GOROOT
+	if goroot == "" || len(pass.Files) == 0 {
+		return false
+	}
+	file := pass.Fset.File(pass.Files[0].FileStart)
+	if file == nil {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Join(goroot, "src"), file.Name())
+	return err == nil && filepath.IsLocal(rel)
+}
+
+func loadRootPackagePaths(args []string) (internal.Set[string], error) {
+	// When run by 'go vet -vettool', each invocation is for a single
+	// package, described by a config file.
+	if len(args) == 1 && strings.HasSuffix(args[0], ".cfg") {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return nil, err
+		}
+		var cfg struct {
+			ImportPath string
+			VetxOnly   bool
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.VetxOnly {
+			return internal.Set[string]{}, nil
+		}
+		return nil, nil
+	}
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName}, args...)
+	if err != nil {
+		return nil, err
+	}
+	paths := internal.Set[string]{}
+	for _, pkg := range pkgs {
+		paths.Add(pkg.PkgPath)
+	}
+	return paths, nil
+}
@@ -0,0 +1,3 @@
+package check
+
+const missingQuery = `SELECT * FROM repo WHERE name = {{name : string}}` // want `missing generated file missing_query_gen.go` missingQuery:`query\(.*\)`
@@ -0,0 +1,35 @@
+// Code generated by querygen.
+// You may only edit import statements.
+package check // want `orphaned generated file orphaned_query_gen.go`
+
+import (
+	"github.com/sourcegraph/querygen/lib/interpolate"
+)
+
+type oldQueryVars struct {
+	old bool
+}
+
+var _ interpolate.QueryVars = &oldQueryVars{}
+
+const (
+	// oldQueryVarsFormat is the sqlf format string for oldQuery.
+	oldQueryVarsFormat = `SELECT * FROM repo WHERE old = %s`
+	// oldQueryVarsArgCount is the number of arguments for oldQueryVarsFormat.
+	oldQueryVarsArgCount = 1
+)
+
+func (qp *oldQueryVars) FormatArgs() []any {
+	return []any{qp.old}
+}
+
+// Build creates a *sqlf.Query from oldQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *oldQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(oldQueryVarsFormat, oldQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *oldQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(oldQueryVarsFormat, oldQueryVarsArgCount, qp)
+}
@@ -0,0 +1,5 @@
+package check
+
+const firstQuery = `SELECT * FROM repo WHERE id = {{id : int}}` // want firstQuery:`query\(.*\)`
+
+const secondQuery = `SELECT * FROM repo WHERE name = {{name : string}}` // want `stale generated file queries_query_gen.go` secondQuery:`query\(.*\)`
@@ -0,0 +1,63 @@
+// Code generated by querygen.
+// You may only edit import statements.
+package check
+
+import (
+	"github.com/sourcegraph/querygen/lib/interpolate"
+)
+
+type firstQueryVars struct {
+	id int
+}
+
+var _ interpolate.QueryVars = &firstQueryVars{}
+
+const (
+	// firstQueryVarsFormat is the sqlf format string for firstQuery.
+	firstQueryVarsFormat = `SELECT * FROM repo WHERE id = %s`
+	// firstQueryVarsArgCount is the number of arguments for firstQueryVarsFormat.
+	firstQueryVarsArgCount = 1
+)
+
+func (qp *firstQueryVars) FormatArgs() []any {
+	return []any{qp.id}
+}
+
+// Build creates a *sqlf.Query from firstQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *firstQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(firstQueryVarsFormat, firstQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *firstQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(firstQueryVarsFormat, firstQueryVarsArgCount, qp)
+}
+
+type secondQueryVars struct {
+	name []byte
+}
+
+var _ interpolate.QueryVars = &secondQueryVars{}
+
+const (
+	// secondQueryVarsFormat is the sqlf format string for secondQuery.
+	secondQueryVarsFormat = `SELECT * FROM repo WHERE name = %s`
+	// secondQueryVarsArgCount is the number of arguments for secondQueryVarsFormat.
+	secondQueryVarsArgCount = 1
+)
+
+func (qp *secondQueryVars) FormatArgs() []any {
+	return []any{qp.name}
+}
+
+// Build creates a *sqlf.Query from secondQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *secondQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(secondQueryVarsFormat, secondQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *secondQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(secondQueryVarsFormat, secondQueryVarsArgCount, qp)
+}
@@ -0,0 +1,4 @@
+package conflicts
+
+//querygen:name=RepoVars
+const repoByNameQuery = `SELECT * FROM repo WHERE name = {{name : string}}` // want `missing generated file repos_query_gen.go` repoByNameQuery:`query\(.*\)`
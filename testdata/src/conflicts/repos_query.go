@@ -0,0 +1,6 @@
+package conflicts
+
+// Maps to the same generated file as repos.go.
+
+//querygen:name=RepoVars
+const repoByIdQuery = `SELECT * FROM repo WHERE id = {{id : int}}` // want `generated type RepoVars for repoByIdQuery in repos_query.go conflicts with the one for repoByNameQuery in repos.go` repoByIdQuery:`query\(.*\)`
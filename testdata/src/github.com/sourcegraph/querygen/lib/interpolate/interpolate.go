@@ -0,0 +1,24 @@
+// Package interpolate is a stub of the runtime package used by generated
+// code, so that the test packages type-check without its dependencies.
+package interpolate
+
+type Query struct {
+	Format string
+	Args   []any
+}
+
+type QueryVars interface {
+	FormatArgs() []any
+}
+
+func DoFormat(format string, argCount int, q QueryVars) (*Query, error) {
+	return &Query{Format: format, Args: q.FormatArgs()}, nil
+}
+
+func MustDoFormat(format string, argCount int, q QueryVars) *Query {
+	query, err := DoFormat(format, argCount, q)
+	if err != nil {
+		panic(err)
+	}
+	return query
+}
@@ -0,0 +1,16 @@
+package modes
+
+import "time"
+
+// Uses the import, which is otherwise only referred to by timesQuery.
+var _ time.Time
+
+const timesQuery = `SELECT * FROM t WHERE at = ANY({{times : []time.Time : array}})` // want `array mode requires a slice of booleans, integers, floats, strings or \[\]byte \(or pointers to them\), but times has element type time.Time`
+
+const matrixQuery = `SELECT * FROM t WHERE id = ANY({{ids : [][]int : array}})` // want `array mode requires .*, but ids has element type \[\]int`
+
+const labelsQuery = `SELECT * FROM t WHERE labels = ANY({{labels : []map[string]string : array}})` // want `array mode requires .*, but labels has element type map\[string\]string`
+
+const idQuery = `SELECT * FROM t WHERE id IN ({{id : int : list}})` // want `list mode requires a slice type, but id has type int`
+
+const nameQuery = `SELECT * FROM t WHERE name = ANY({{name : string : array}})` // want `array mode requires a slice type, but name has type string`
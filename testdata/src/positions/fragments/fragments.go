@@ -0,0 +1,3 @@
+package fragments
+
+const RepoFilterQueryFragment = `name = {{name : string}}`
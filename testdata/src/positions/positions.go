@@ -0,0 +1,14 @@
+package positions
+
+import "positions/fragments"
+
+// Diagnostics for interpolations are reported inside the string literals
+// the query was folded from, or at the reference to an imported fragment.
+
+const multiLineQuery = "SELECT * FROM repo " +
+	"WHERE id = {{id int}}" // want `ill-formed interpolation in multiLineQuery: expected ':' after field name id but found "int"`
+
+const escapedQuery = "SELECT * FROM repo WHERE id = \x7b\x7bid int}}" // want `ill-formed interpolation in escapedQuery: expected ':' after field name id but found "int"`
+
+const importedQuery = `SELECT * FROM repo WHERE owner = {{name : int}} AND ` +
+	fragments.RepoFilterQueryFragment // want `ill-formed interpolation in importedQuery: field name used with distinct types: int and string`
@@ -0,0 +1,5 @@
+package write // want `handwritten_query_gen.go was not generated by querygen`
+
+// helper is hand-written, and must not be removed even though
+// handwritten.go doesn't have any queries.
+func helper() {}
@@ -0,0 +1,3 @@
+package write
+
+const missingQuery = `SELECT * FROM repo WHERE name = {{name : string}}` // want missingQuery:`query\(.*\)`
@@ -0,0 +1,3 @@
+package write
+
+const staleQuery = `SELECT * FROM repo WHERE id = {{id : int}}` // want staleQuery:`query\(.*\)`
@@ -0,0 +1,35 @@
+// Code generated by querygen.
+// You may only edit import statements.
+package write
+
+import (
+	"github.com/sourcegraph/querygen/lib/interpolate"
+)
+
+type staleQueryVars struct {
+	id string
+}
+
+var _ interpolate.QueryVars = &staleQueryVars{}
+
+const (
+	// staleQueryVarsFormat is the sqlf format string for staleQuery.
+	staleQueryVarsFormat = `SELECT * FROM repo WHERE id = %s`
+	// staleQueryVarsArgCount is the number of arguments for staleQueryVarsFormat.
+	staleQueryVarsArgCount = 1
+)
+
+func (qp *staleQueryVars) FormatArgs() []any {
+	return []any{qp.id}
+}
+
+// Build creates a *sqlf.Query from staleQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *staleQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(staleQueryVarsFormat, staleQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *staleQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(staleQueryVarsFormat, staleQueryVarsArgCount, qp)
+}
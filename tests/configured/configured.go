@@ -0,0 +1,12 @@
+package configured
+
+//querygen:query
+const listReposSQL = `SELECT * FROM repo WHERE name = {{name: string}} {{#if !includeDeleted}}AND deleted_at IS NULL {{/if}}LIMIT {{limit: int}}`
+
+// Not a query, since tests/configured requires //querygen:query.
+const listUsersQuery = `SELECT * FROM users WHERE name = {{name: string}}`
+
+// Leading underscores are dropped from exported field names.
+//
+//querygen:query
+const pagedReposSQL = `SELECT * FROM repo ORDER BY id LIMIT {{limit: int}} OFFSET {{_offset: int}}`
@@ -0,0 +1,69 @@
+// Copyright Sourcegraph, Inc.
+// Licensed under the Apache License, Version 2.0.
+
+// Code generated by querygen.
+// You may only edit import statements.
+package configured
+
+import (
+	"github.com/sourcegraph/querygen/lib/interpolate"
+)
+
+type listReposSQLVars struct {
+	Name           string
+	IncludeDeleted bool
+	Limit          int
+}
+
+var _ interpolate.QueryVars = &listReposSQLVars{}
+
+const (
+	// listReposSQLVarsFormat is the sqlf format string for listReposSQL.
+	listReposSQLVarsFormat = `SELECT * FROM repo WHERE name = %s %sLIMIT %s`
+	// listReposSQLVarsArgCount is the number of arguments for listReposSQLVarsFormat.
+	listReposSQLVarsArgCount = 3
+)
+
+func (qp *listReposSQLVars) FormatArgs() []any {
+	return []any{qp.Name, interpolate.Section(!qp.IncludeDeleted, `AND deleted_at IS NULL `), qp.Limit}
+}
+
+// Build creates a *sqlf.Query from listReposSQL using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *listReposSQLVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(listReposSQLVarsFormat, listReposSQLVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *listReposSQLVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(listReposSQLVarsFormat, listReposSQLVarsArgCount, qp)
+}
+
+type pagedReposSQLVars struct {
+	Limit  int
+	Offset int
+}
+
+var _ interpolate.QueryVars = &pagedReposSQLVars{}
+
+const (
+	// pagedReposSQLVarsFormat is the sqlf format string for pagedReposSQL.
+	pagedReposSQLVarsFormat = `SELECT * FROM repo ORDER BY id LIMIT %s OFFSET %s`
+	// pagedReposSQLVarsArgCount is the number of arguments for pagedReposSQLVarsFormat.
+	pagedReposSQLVarsArgCount = 2
+)
+
+func (qp *pagedReposSQLVars) FormatArgs() []any {
+	return []any{qp.Limit, qp.Offset}
+}
+
+// Build creates a *sqlf.Query from pagedReposSQL using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *pagedReposSQLVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(pagedReposSQLVarsFormat, pagedReposSQLVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *pagedReposSQLVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(pagedReposSQLVarsFormat, pagedReposSQLVarsArgCount, qp)
+}
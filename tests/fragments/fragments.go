@@ -0,0 +1,15 @@
+// Package fragments contains query fragments shared across packages.
+package fragments
+
+import "time"
+
+type Text string
+
+type RepoID int
+
+var _ time.Time
+
+const RepoFilterQueryFragment = `repo_id = {{repoID: RepoID}}`
+
+// VisibleRepoQueryFragment is a typed constant.
+const VisibleRepoQueryFragment Text = `(NOT repo.private OR repo.owner_id = {{userID: int}}) AND repo.created_at < {{before: time.Time}}`
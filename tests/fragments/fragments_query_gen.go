@@ -0,0 +1,66 @@
+// Code generated by querygen.
+// You may only edit import statements.
+package fragments
+
+import (
+	"time"
+
+	"github.com/sourcegraph/querygen/lib/interpolate"
+)
+
+type RepoFilterQueryFragmentVars struct {
+	repoID RepoID
+}
+
+var _ interpolate.QueryVars = &RepoFilterQueryFragmentVars{}
+
+const (
+	// RepoFilterQueryFragmentVarsFormat is the sqlf format string for RepoFilterQueryFragment.
+	RepoFilterQueryFragmentVarsFormat = `repo_id = %s`
+	// RepoFilterQueryFragmentVarsArgCount is the number of arguments for RepoFilterQueryFragmentVarsFormat.
+	RepoFilterQueryFragmentVarsArgCount = 1
+)
+
+func (qp *RepoFilterQueryFragmentVars) FormatArgs() []any {
+	return []any{qp.repoID}
+}
+
+// Build creates a *sqlf.Query from RepoFilterQueryFragment using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *RepoFilterQueryFragmentVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(RepoFilterQueryFragmentVarsFormat, RepoFilterQueryFragmentVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *RepoFilterQueryFragmentVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(RepoFilterQueryFragmentVarsFormat, RepoFilterQueryFragmentVarsArgCount, qp)
+}
+
+type VisibleRepoQueryFragmentVars struct {
+	userID int
+	before time.Time
+}
+
+var _ interpolate.QueryVars = &VisibleRepoQueryFragmentVars{}
+
+const (
+	// VisibleRepoQueryFragmentVarsFormat is the sqlf format string for VisibleRepoQueryFragment.
+	VisibleRepoQueryFragmentVarsFormat = `(NOT repo.private OR repo.owner_id = %s) AND repo.created_at < %s`
+	// VisibleRepoQueryFragmentVarsArgCount is the number of arguments for VisibleRepoQueryFragmentVarsFormat.
+	VisibleRepoQueryFragmentVarsArgCount = 2
+)
+
+func (qp *VisibleRepoQueryFragmentVars) FormatArgs() []any {
+	return []any{qp.userID, qp.before}
+}
+
+// Build creates a *sqlf.Query from VisibleRepoQueryFragment using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *VisibleRepoQueryFragmentVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(VisibleRepoQueryFragmentVarsFormat, VisibleRepoQueryFragmentVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *VisibleRepoQueryFragmentVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(VisibleRepoQueryFragmentVarsFormat, VisibleRepoQueryFragmentVarsArgCount, qp)
+}
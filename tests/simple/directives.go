@@ -0,0 +1,16 @@
+package simple
+
+//querygen:query
+const listReposSQL = `SELECT * FROM repo WHERE name = {{name: string}}`
+
+//querygen:ignore
+const legacyQuery = `SELECT * FROM {{not an interpolation}}`
+
+const (
+	//querygen:query
+	//querygen:name=RepoCountVars
+	countReposStmt = `SELECT count(*) FROM repo WHERE owner = {{owner: string}}`
+
+	// Not a query, since it has no directive and doesn't end in Query.
+	unrelatedStmt = `{{x: int}}`
+)
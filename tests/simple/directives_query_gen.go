@@ -0,0 +1,63 @@
+// Code generated by querygen.
+// You may only edit import statements.
+package simple
+
+import (
+	"github.com/sourcegraph/querygen/lib/interpolate"
+)
+
+type listReposSQLVars struct {
+	name string
+}
+
+var _ interpolate.QueryVars = &listReposSQLVars{}
+
+const (
+	// listReposSQLVarsFormat is the sqlf format string for listReposSQL.
+	listReposSQLVarsFormat = `SELECT * FROM repo WHERE name = %s`
+	// listReposSQLVarsArgCount is the number of arguments for listReposSQLVarsFormat.
+	listReposSQLVarsArgCount = 1
+)
+
+func (qp *listReposSQLVars) FormatArgs() []any {
+	return []any{qp.name}
+}
+
+// Build creates a *sqlf.Query from listReposSQL using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *listReposSQLVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(listReposSQLVarsFormat, listReposSQLVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *listReposSQLVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(listReposSQLVarsFormat, listReposSQLVarsArgCount, qp)
+}
+
+type RepoCountVars struct {
+	owner string
+}
+
+var _ interpolate.QueryVars = &RepoCountVars{}
+
+const (
+	// RepoCountVarsFormat is the sqlf format string for countReposStmt.
+	RepoCountVarsFormat = `SELECT count(*) FROM repo WHERE owner = %s`
+	// RepoCountVarsArgCount is the number of arguments for RepoCountVarsFormat.
+	RepoCountVarsArgCount = 1
+)
+
+func (qp *RepoCountVars) FormatArgs() []any {
+	return []any{qp.owner}
+}
+
+// Build creates a *sqlf.Query from countReposStmt using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *RepoCountVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(RepoCountVarsFormat, RepoCountVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *RepoCountVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(RepoCountVarsFormat, RepoCountVarsArgCount, qp)
+}
@@ -0,0 +1,4 @@
+package simple
+
+// Interpolations are found in the unquoted value, even if spelled with escapes.
+const escapedQuery = "SELECT * FROM \"users\"\nWHERE name = {{name: string}} AND id = \x7b\x7bid: int}}"
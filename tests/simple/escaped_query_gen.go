@@ -0,0 +1,37 @@
+// Code generated by querygen.
+// You may only edit import statements.
+package simple
+
+import (
+	"github.com/sourcegraph/querygen/lib/interpolate"
+)
+
+type escapedQueryVars struct {
+	name string
+	id   int
+}
+
+var _ interpolate.QueryVars = &escapedQueryVars{}
+
+const (
+	// escapedQueryVarsFormat is the sqlf format string for escapedQuery.
+	escapedQueryVarsFormat = `SELECT * FROM "users"
+WHERE name = %s AND id = %s`
+	// escapedQueryVarsArgCount is the number of arguments for escapedQueryVarsFormat.
+	escapedQueryVarsArgCount = 2
+)
+
+func (qp *escapedQueryVars) FormatArgs() []any {
+	return []any{qp.name, qp.id}
+}
+
+// Build creates a *sqlf.Query from escapedQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *escapedQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(escapedQueryVarsFormat, escapedQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *escapedQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(escapedQueryVarsFormat, escapedQueryVarsArgCount, qp)
+}
@@ -0,0 +1,8 @@
+package simple
+
+import "github.com/sourcegraph/querygen/tests/fragments"
+
+const repoCommitsQuery = `SELECT * FROM commits WHERE ` + fragments.RepoFilterQueryFragment +
+	(` AND author = {{author: string}} AND parent_repo_id != {{repoID: _}}`)
+
+const visibleReposQuery = "SELECT * FROM repo WHERE " + string(fragments.VisibleRepoQueryFragment)
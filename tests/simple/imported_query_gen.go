@@ -0,0 +1,68 @@
+// Code generated by querygen.
+// You may only edit import statements.
+package simple
+
+import (
+	"time"
+
+	"github.com/sourcegraph/querygen/lib/interpolate"
+	"github.com/sourcegraph/querygen/tests/fragments"
+)
+
+type repoCommitsQueryVars struct {
+	repoID fragments.RepoID
+	author string
+}
+
+var _ interpolate.QueryVars = &repoCommitsQueryVars{}
+
+const (
+	// repoCommitsQueryVarsFormat is the sqlf format string for repoCommitsQuery.
+	repoCommitsQueryVarsFormat = `SELECT * FROM commits WHERE repo_id = %s AND author = %s AND parent_repo_id != %s`
+	// repoCommitsQueryVarsArgCount is the number of arguments for repoCommitsQueryVarsFormat.
+	repoCommitsQueryVarsArgCount = 3
+)
+
+func (qp *repoCommitsQueryVars) FormatArgs() []any {
+	return []any{qp.repoID, qp.author, qp.repoID}
+}
+
+// Build creates a *sqlf.Query from repoCommitsQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *repoCommitsQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(repoCommitsQueryVarsFormat, repoCommitsQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *repoCommitsQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(repoCommitsQueryVarsFormat, repoCommitsQueryVarsArgCount, qp)
+}
+
+type visibleReposQueryVars struct {
+	userID int
+	before time.Time
+}
+
+var _ interpolate.QueryVars = &visibleReposQueryVars{}
+
+const (
+	// visibleReposQueryVarsFormat is the sqlf format string for visibleReposQuery.
+	visibleReposQueryVarsFormat = `SELECT * FROM repo WHERE (NOT repo.private OR repo.owner_id = %s) AND repo.created_at < %s`
+	// visibleReposQueryVarsArgCount is the number of arguments for visibleReposQueryVarsFormat.
+	visibleReposQueryVarsArgCount = 2
+)
+
+func (qp *visibleReposQueryVars) FormatArgs() []any {
+	return []any{qp.userID, qp.before}
+}
+
+// Build creates a *sqlf.Query from visibleReposQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *visibleReposQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(visibleReposQueryVarsFormat, visibleReposQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *visibleReposQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(visibleReposQueryVarsFormat, visibleReposQueryVarsArgCount, qp)
+}
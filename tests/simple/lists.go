@@ -0,0 +1,9 @@
+package simple
+
+type RepoIDs []int32
+
+const reposByIDQuery = `SELECT name FROM repo WHERE id IN ({{ids : RepoIDs : list : %d}})`
+
+const insertReposQuery = `INSERT INTO repo (name) VALUES ({{names : []string : list}})`
+
+const reposByNameQuery = `SELECT id FROM repo WHERE name = ANY({{names : []string : array}}) AND id <> ALL({{excluded : []*int64 : array}})`
@@ -0,0 +1,92 @@
+// Code generated by querygen.
+// You may only edit import statements.
+package simple
+
+import (
+	"github.com/sourcegraph/querygen/lib/interpolate"
+)
+
+type reposByIDQueryVars struct {
+	ids RepoIDs
+}
+
+var _ interpolate.QueryVars = &reposByIDQueryVars{}
+
+const (
+	// reposByIDQueryVarsFormat is the sqlf format string for reposByIDQuery.
+	reposByIDQueryVarsFormat = `SELECT name FROM repo WHERE id IN (%s)`
+	// reposByIDQueryVarsArgCount is the number of arguments for reposByIDQueryVarsFormat.
+	reposByIDQueryVarsArgCount = 1
+)
+
+func (qp *reposByIDQueryVars) FormatArgs() []any {
+	return []any{interpolate.List(qp.ids, "%d")}
+}
+
+// Build creates a *sqlf.Query from reposByIDQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *reposByIDQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(reposByIDQueryVarsFormat, reposByIDQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *reposByIDQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(reposByIDQueryVarsFormat, reposByIDQueryVarsArgCount, qp)
+}
+
+type insertReposQueryVars struct {
+	names []string
+}
+
+var _ interpolate.QueryVars = &insertReposQueryVars{}
+
+const (
+	// insertReposQueryVarsFormat is the sqlf format string for insertReposQuery.
+	insertReposQueryVarsFormat = `INSERT INTO repo (name) VALUES (%s)`
+	// insertReposQueryVarsArgCount is the number of arguments for insertReposQueryVarsFormat.
+	insertReposQueryVarsArgCount = 1
+)
+
+func (qp *insertReposQueryVars) FormatArgs() []any {
+	return []any{interpolate.List(qp.names, "%s")}
+}
+
+// Build creates a *sqlf.Query from insertReposQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *insertReposQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(insertReposQueryVarsFormat, insertReposQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *insertReposQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(insertReposQueryVarsFormat, insertReposQueryVarsArgCount, qp)
+}
+
+type reposByNameQueryVars struct {
+	names    []string
+	excluded []*int64
+}
+
+var _ interpolate.QueryVars = &reposByNameQueryVars{}
+
+const (
+	// reposByNameQueryVarsFormat is the sqlf format string for reposByNameQuery.
+	reposByNameQueryVarsFormat = `SELECT id FROM repo WHERE name = ANY(%s) AND id <> ALL(%s)`
+	// reposByNameQueryVarsArgCount is the number of arguments for reposByNameQueryVarsFormat.
+	reposByNameQueryVarsArgCount = 2
+)
+
+func (qp *reposByNameQueryVars) FormatArgs() []any {
+	return []any{interpolate.Array(qp.names), interpolate.Array(qp.excluded)}
+}
+
+// Build creates a *sqlf.Query from reposByNameQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *reposByNameQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(reposByNameQueryVarsFormat, reposByNameQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *reposByNameQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(reposByNameQueryVarsFormat, reposByNameQueryVarsArgCount, qp)
+}
@@ -0,0 +1,16 @@
+package simple
+
+import (
+	stdtime "time"
+
+	"github.com/keegancsmith/sqlf"
+)
+
+var (
+	_ stdtime.Time
+	_ *sqlf.Query
+)
+
+const recentEventsQuery = `SELECT * FROM events WHERE created_at > {{since: stdtime.Time}} AND {{extraCond: *sqlf.Query}}`
+
+const searchEventsQuery = `SELECT * FROM events WHERE {{conds: []*sqlf.Query: join " AND ": empty "TRUE"}} ORDER BY {{orderBy: []*sqlf.Query: join ", "}}`
@@ -0,0 +1,3 @@
+package simple
+
+const eventCountQuery = `SELECT count(*) FROM events WHERE kind = {{kind: string}}`
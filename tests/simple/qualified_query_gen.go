@@ -0,0 +1,100 @@
+// Code generated by querygen.
+// You may only edit import statements.
+package simple
+
+import (
+	stdtime "time"
+
+	"github.com/keegancsmith/sqlf"
+	"github.com/sourcegraph/querygen/lib/interpolate"
+)
+
+// Generated from queries in qualified.go.
+
+type recentEventsQueryVars struct {
+	since     stdtime.Time
+	extraCond *sqlf.Query
+}
+
+var _ interpolate.QueryVars = &recentEventsQueryVars{}
+
+const (
+	// recentEventsQueryVarsFormat is the sqlf format string for recentEventsQuery.
+	recentEventsQueryVarsFormat = `SELECT * FROM events WHERE created_at > %s AND %s`
+	// recentEventsQueryVarsArgCount is the number of arguments for recentEventsQueryVarsFormat.
+	recentEventsQueryVarsArgCount = 2
+)
+
+func (qp *recentEventsQueryVars) FormatArgs() []any {
+	return []any{qp.since, qp.extraCond}
+}
+
+// Build creates a *sqlf.Query from recentEventsQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *recentEventsQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(recentEventsQueryVarsFormat, recentEventsQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *recentEventsQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(recentEventsQueryVarsFormat, recentEventsQueryVarsArgCount, qp)
+}
+
+type searchEventsQueryVars struct {
+	conds   []*sqlf.Query
+	orderBy []*sqlf.Query
+}
+
+var _ interpolate.QueryVars = &searchEventsQueryVars{}
+
+const (
+	// searchEventsQueryVarsFormat is the sqlf format string for searchEventsQuery.
+	searchEventsQueryVarsFormat = `SELECT * FROM events WHERE %s ORDER BY %s`
+	// searchEventsQueryVarsArgCount is the number of arguments for searchEventsQueryVarsFormat.
+	searchEventsQueryVarsArgCount = 2
+)
+
+func (qp *searchEventsQueryVars) FormatArgs() []any {
+	return []any{interpolate.List(qp.conds, "%s").WithSeparator(" AND ").OrEmpty("TRUE"), interpolate.List(qp.orderBy, "%s")}
+}
+
+// Build creates a *sqlf.Query from searchEventsQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *searchEventsQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(searchEventsQueryVarsFormat, searchEventsQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *searchEventsQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(searchEventsQueryVarsFormat, searchEventsQueryVarsArgCount, qp)
+}
+
+// Generated from queries in qualified_queries.go.
+
+type eventCountQueryVars struct {
+	kind string
+}
+
+var _ interpolate.QueryVars = &eventCountQueryVars{}
+
+const (
+	// eventCountQueryVarsFormat is the sqlf format string for eventCountQuery.
+	eventCountQueryVarsFormat = `SELECT count(*) FROM events WHERE kind = %s`
+	// eventCountQueryVarsArgCount is the number of arguments for eventCountQueryVarsFormat.
+	eventCountQueryVarsArgCount = 1
+)
+
+func (qp *eventCountQueryVars) FormatArgs() []any {
+	return []any{qp.kind}
+}
+
+// Build creates a *sqlf.Query from eventCountQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *eventCountQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(eventCountQueryVarsFormat, eventCountQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *eventCountQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(eventCountQueryVarsFormat, eventCountQueryVarsArgCount, qp)
+}
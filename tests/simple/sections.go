@@ -0,0 +1,10 @@
+package simple
+
+const listReposQuery = `
+SELECT id FROM repo
+WHERE name LIKE {{pattern: string}}
+{{#if !includeDeleted}}AND deleted_at IS NULL{{/if}}
+{{#if onlyForks}}AND fork AND parent_id IN ({{parentIDs: []int32: list: %d}}){{/if}}
+ORDER BY id`
+
+const repoCountQuery = `SELECT COUNT(*) FROM repo {{#if private}}WHERE private = {{private: _}}{{/if}}`
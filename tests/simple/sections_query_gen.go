@@ -0,0 +1,71 @@
+// Code generated by querygen.
+// You may only edit import statements.
+package simple
+
+import (
+	"github.com/sourcegraph/querygen/lib/interpolate"
+)
+
+type listReposQueryVars struct {
+	pattern        string
+	includeDeleted bool
+	onlyForks      bool
+	parentIDs      []int32
+}
+
+var _ interpolate.QueryVars = &listReposQueryVars{}
+
+const (
+	// listReposQueryVarsFormat is the sqlf format string for listReposQuery.
+	listReposQueryVarsFormat = `
+SELECT id FROM repo
+WHERE name LIKE %s
+%s
+%s
+ORDER BY id`
+	// listReposQueryVarsArgCount is the number of arguments for listReposQueryVarsFormat.
+	listReposQueryVarsArgCount = 3
+)
+
+func (qp *listReposQueryVars) FormatArgs() []any {
+	return []any{qp.pattern, interpolate.Section(!qp.includeDeleted, `AND deleted_at IS NULL`), interpolate.Section(qp.onlyForks, `AND fork AND parent_id IN (%s)`, interpolate.List(qp.parentIDs, "%d"))}
+}
+
+// Build creates a *sqlf.Query from listReposQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *listReposQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(listReposQueryVarsFormat, listReposQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *listReposQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(listReposQueryVarsFormat, listReposQueryVarsArgCount, qp)
+}
+
+type repoCountQueryVars struct {
+	private bool
+}
+
+var _ interpolate.QueryVars = &repoCountQueryVars{}
+
+const (
+	// repoCountQueryVarsFormat is the sqlf format string for repoCountQuery.
+	repoCountQueryVarsFormat = `SELECT COUNT(*) FROM repo %s`
+	// repoCountQueryVarsArgCount is the number of arguments for repoCountQueryVarsFormat.
+	repoCountQueryVarsArgCount = 1
+)
+
+func (qp *repoCountQueryVars) FormatArgs() []any {
+	return []any{interpolate.Section(qp.private, `WHERE private = %s`, qp.private)}
+}
+
+// Build creates a *sqlf.Query from repoCountQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *repoCountQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(repoCountQueryVarsFormat, repoCountQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *repoCountQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(repoCountQueryVarsFormat, repoCountQueryVarsArgCount, qp)
+}
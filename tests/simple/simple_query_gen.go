@@ -12,6 +12,24 @@ type selectAllQueryVars struct {
 
 var _ interpolate.QueryVars = &selectAllQueryVars{}
 
+const (
+	// selectAllQueryVarsFormat is the sqlf format string for selectAllQuery.
+	selectAllQueryVarsFormat = `SELECT * from %s`
+	// selectAllQueryVarsArgCount is the number of arguments for selectAllQueryVarsFormat.
+	selectAllQueryVarsArgCount = 1
+)
+
 func (qp *selectAllQueryVars) FormatArgs() []any {
 	return []any{qp.tableName}
 }
+
+// Build creates a *sqlf.Query from selectAllQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *selectAllQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(selectAllQueryVarsFormat, selectAllQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *selectAllQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(selectAllQueryVarsFormat, selectAllQueryVarsArgCount, qp)
+}
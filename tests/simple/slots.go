@@ -0,0 +1,11 @@
+package simple
+
+import "github.com/sourcegraph/querygen/tests/fragments"
+
+var _ = fragments.RepoFilterQueryFragment
+
+const ownerFilterQueryFragment = `owner = {{owner : string}}`
+
+const filteredReposQuery = `SELECT id FROM repo WHERE {{ownerCond : fragment(ownerFilterQueryFragment)}} AND {{extraCond : fragment}}`
+
+const filteredCommitsQuery = `SELECT * FROM commits WHERE {{repoCond : fragment(fragments.RepoFilterQueryFragment)}}`
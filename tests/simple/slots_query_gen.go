@@ -0,0 +1,93 @@
+// Code generated by querygen.
+// You may only edit import statements.
+package simple
+
+import (
+	"github.com/sourcegraph/querygen/lib/interpolate"
+	"github.com/sourcegraph/querygen/tests/fragments"
+)
+
+type ownerFilterQueryFragmentVars struct {
+	owner string
+}
+
+var _ interpolate.QueryVars = &ownerFilterQueryFragmentVars{}
+
+const (
+	// ownerFilterQueryFragmentVarsFormat is the sqlf format string for ownerFilterQueryFragment.
+	ownerFilterQueryFragmentVarsFormat = `owner = %s`
+	// ownerFilterQueryFragmentVarsArgCount is the number of arguments for ownerFilterQueryFragmentVarsFormat.
+	ownerFilterQueryFragmentVarsArgCount = 1
+)
+
+func (qp *ownerFilterQueryFragmentVars) FormatArgs() []any {
+	return []any{qp.owner}
+}
+
+// Build creates a *sqlf.Query from ownerFilterQueryFragment using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *ownerFilterQueryFragmentVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(ownerFilterQueryFragmentVarsFormat, ownerFilterQueryFragmentVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *ownerFilterQueryFragmentVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(ownerFilterQueryFragmentVarsFormat, ownerFilterQueryFragmentVarsArgCount, qp)
+}
+
+type filteredReposQueryVars struct {
+	ownerCond interpolate.Fragment[*ownerFilterQueryFragmentVars]
+	extraCond *interpolate.Query
+}
+
+var _ interpolate.QueryVars = &filteredReposQueryVars{}
+
+const (
+	// filteredReposQueryVarsFormat is the sqlf format string for filteredReposQuery.
+	filteredReposQueryVarsFormat = `SELECT id FROM repo WHERE %s AND %s`
+	// filteredReposQueryVarsArgCount is the number of arguments for filteredReposQueryVarsFormat.
+	filteredReposQueryVarsArgCount = 2
+)
+
+func (qp *filteredReposQueryVars) FormatArgs() []any {
+	return []any{qp.ownerCond, qp.extraCond}
+}
+
+// Build creates a *sqlf.Query from filteredReposQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *filteredReposQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(filteredReposQueryVarsFormat, filteredReposQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *filteredReposQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(filteredReposQueryVarsFormat, filteredReposQueryVarsArgCount, qp)
+}
+
+type filteredCommitsQueryVars struct {
+	repoCond interpolate.Fragment[*fragments.RepoFilterQueryFragmentVars]
+}
+
+var _ interpolate.QueryVars = &filteredCommitsQueryVars{}
+
+const (
+	// filteredCommitsQueryVarsFormat is the sqlf format string for filteredCommitsQuery.
+	filteredCommitsQueryVarsFormat = `SELECT * FROM commits WHERE %s`
+	// filteredCommitsQueryVarsArgCount is the number of arguments for filteredCommitsQueryVarsFormat.
+	filteredCommitsQueryVarsArgCount = 1
+)
+
+func (qp *filteredCommitsQueryVars) FormatArgs() []any {
+	return []any{qp.repoCond}
+}
+
+// Build creates a *sqlf.Query from filteredCommitsQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *filteredCommitsQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(filteredCommitsQueryVarsFormat, filteredCommitsQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *filteredCommitsQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(filteredCommitsQueryVarsFormat, filteredCommitsQueryVarsArgCount, qp)
+}
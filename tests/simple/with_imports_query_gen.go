@@ -3,8 +3,9 @@
 package simple
 
 import (
-	"github.com/sourcegraph/querygen/lib/interpolate"
 	_ "math" // Added by hand
+
+	"github.com/sourcegraph/querygen/lib/interpolate"
 )
 
 type myQueryVars struct {
@@ -13,6 +14,24 @@ type myQueryVars struct {
 
 var _ interpolate.QueryVars = &myQueryVars{}
 
+const (
+	// myQueryVarsFormat is the sqlf format string for myQuery.
+	myQueryVarsFormat = `SELECT * from %s`
+	// myQueryVarsArgCount is the number of arguments for myQueryVarsFormat.
+	myQueryVarsArgCount = 1
+)
+
 func (qp *myQueryVars) FormatArgs() []any {
 	return []any{qp.abc}
 }
+
+// Build creates a *sqlf.Query from myQuery using these vars.
+// It returns an error for an empty list or an unset fragment.
+func (qp *myQueryVars) Build() (*interpolate.Query, error) {
+	return interpolate.DoFormat(myQueryVarsFormat, myQueryVarsArgCount, qp)
+}
+
+// MustBuild is like Build but panics if Build returns an error.
+func (qp *myQueryVars) MustBuild() *interpolate.Query {
+	return interpolate.MustDoFormat(myQueryVarsFormat, myQueryVarsArgCount, qp)
+}
@@ -0,0 +1,112 @@
+package querygen
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nightlyone/lockfile"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// claimedPaths tracks the generated files which have been handled
+// in this process.
+//
+// The analysis driver may run concurrently on multiple variants of
+// the same package (e.g. pkg and the test variant of pkg), which share
+// source files, and hence generated files. Only the first pass to
+// claim a path gets to write it.
+var claimedPaths = struct {
+	sync.Mutex
+	paths map[string]struct{}
+}{paths: map[string]struct{}{}}
+
+// claimPath returns true if path hasn't been claimed before.
+func claimPath(path string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+	claimedPaths.Lock()
+	defer claimedPaths.Unlock()
+	if _, ok := claimedPaths.paths[absPath]; ok {
+		return false
+	}
+	claimedPaths.paths[absPath] = struct{}{}
+	return true
+}
+
+const lockTimeout = 30 * time.Second
+
+// withFileLock runs fn while holding a lock on path, guarding against
+// other querygen processes modifying the same file concurrently.
+//
+// The lock is a separate file next to path, which is removed afterwards.
+// Locks left behind by processes which no longer exist are ignored.
+func withFileLock(path string, fn func() error) (err error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to get absolute path")
+	}
+	lock, err := lockfile.New(filepath.Join(filepath.Dir(absPath), "."+filepath.Base(absPath)+".lock"))
+	if err != nil {
+		return errors.Wrap(err, "failed to create lock")
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		err = lock.TryLock()
+		if err == nil {
+			break
+		}
+		var tempErr lockfile.TemporaryError
+		if !errors.As(err, &tempErr) {
+			return errors.Wrap(err, "failed to acquire lock")
+		}
+		if time.Now().After(deadline) {
+			return errors.Wrapf(err, "timed out waiting for lock on %s", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	defer func() {
+		if unlockErr := lock.Unlock(); unlockErr != nil && err == nil {
+			err = errors.Wrap(unlockErr, "failed to release lock")
+		}
+	}()
+	return fn()
+}
+
+// writeFileAtomic replaces the contents of path with contents.
+//
+// The data is first written to a temporary file in the same directory,
+// which is then renamed over path, so readers (and an interrupted run)
+// will only ever see either the old contents or the new contents.
+func writeFileAtomic(path string, contents []byte) (err error) {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+	defer func() {
+		if err != nil {
+			_ = tmpFile.Close()
+			_ = os.Remove(tmpFile.Name())
+		}
+	}()
+	if _, err := tmpFile.Write(contents); err != nil {
+		return errors.Wrap(err, "failed to write temporary file")
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return errors.Wrap(err, "failed to sync temporary file")
+	}
+	if err := tmpFile.Close(); err != nil {
+		return errors.Wrap(err, "failed to close temporary file")
+	}
+	// CreateTemp uses 0600.
+	if err := os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return errors.Wrap(err, "failed to set permissions on temporary file")
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return errors.Wrap(err, "failed to rename temporary file")
+	}
+	return nil
+}
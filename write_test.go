@@ -0,0 +1,90 @@
+package querygen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimPath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a_query_gen.go")
+	require.True(t, claimPath(path))
+	require.False(t, claimPath(path))
+
+	// Relative paths refer to the same file.
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	require.False(t, claimPath("a_query_gen.go"))
+	require.True(t, claimPath("b_query_gen.go"))
+}
+
+func TestWithFileLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a_query_gen.go")
+	lockPath := filepath.Join(dir, ".a_query_gen.go.lock")
+
+	ran := false
+	require.NoError(t, withFileLock(path, func() error {
+		require.FileExists(t, lockPath)
+		ran = true
+		return nil
+	}))
+	require.True(t, ran)
+	require.NoFileExists(t, lockPath)
+
+	require.ErrorContains(t, withFileLock(path, func() error {
+		return fmt.Errorf("failed")
+	}), "failed")
+	require.NoFileExists(t, lockPath)
+
+	// Waits for a lock held by another process.
+	require.NoError(t, os.WriteFile(lockPath, []byte(fmt.Sprintf("%d\n", os.Getppid())), 0644))
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		close(released)
+		_ = os.Remove(lockPath)
+	}()
+	require.NoError(t, withFileLock(path, func() error {
+		select {
+		case <-released:
+		default:
+			t.Error("ran while the lock was held by another process")
+		}
+		return nil
+	}))
+
+	// Ignores a lock left behind by a process which no longer exists.
+	require.NoError(t, os.WriteFile(lockPath, []byte("999999999\n"), 0644))
+	require.NoError(t, withFileLock(path, func() error { return nil }))
+	require.NoFileExists(t, lockPath)
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a_query_gen.go")
+
+	require.NoError(t, writeFileAtomic(path, []byte("package a\n")))
+	require.NoError(t, writeFileAtomic(path, []byte("package b\n")))
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "package b\n", string(contents))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// The temporary file is removed if it can't replace path.
+	dirPath := filepath.Join(dir, "b_query_gen.go")
+	require.NoError(t, os.Mkdir(dirPath, 0755))
+	require.Error(t, writeFileAtomic(dirPath, []byte("package b\n")))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}